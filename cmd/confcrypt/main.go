@@ -0,0 +1,171 @@
+// Command confcrypt encrypts and decrypts values in .env files in place, so
+// that they can be committed to git and decrypted by conf at load time with
+// conf.WithDecryptionKey or conf.WithKeyFile.
+//
+//	confcrypt keygen > key
+//	confcrypt encrypt --key-file key .env DB_PASSWORD API_KEY
+//	confcrypt decrypt --key-file key .env DB_PASSWORD
+//
+// Instead of --key-file, the base64 encoded key can be set in CONFCRYPT_KEY.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/solhall/conf"
+)
+
+type keyConfig struct {
+	KeyFile string `conf:"key-file" short:"k" usage:"file with the base64 encoded key"`
+	Key     string `conf:"confcrypt-key" usage:"base64 encoded key, instead of --key-file"`
+}
+
+// key returns the key from the key file or the key parameter.
+func (c *keyConfig) key() (*[32]byte, error) {
+	switch {
+	case c.KeyFile != "":
+		content, err := os.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return conf.ParseKey(string(content))
+	case c.Key != "":
+		return conf.ParseKey(c.Key)
+	default:
+		return nil, errors.New("no key given, set --key-file or CONFCRYPT_KEY")
+	}
+}
+
+type valuesConfig struct {
+	keyConfig
+
+	File string   `conf:"file,required" arg:"0" usage:".env file to modify"`
+	Keys []string `conf:"keys" arg:"rest" usage:"variables to modify"`
+}
+
+func main() {
+	var encryptCfg, decryptCfg valuesConfig
+
+	cmd := &conf.Command{
+		Name:  "confcrypt",
+		Usage: "confcrypt encrypts and decrypts values in .env files in place.",
+		Commands: []*conf.Command{
+			{
+				Name:  "keygen",
+				Usage: "print a new random key",
+				Run: func(context.Context, []string) error {
+					key, err := conf.GenerateKey()
+					if err != nil {
+						return err
+					}
+
+					fmt.Println(conf.FormatKey(key))
+					return nil
+				},
+			},
+			{
+				Name:   "encrypt",
+				Usage:  "encrypt the values of variables",
+				Config: &encryptCfg,
+				Run: func(context.Context, []string) error {
+					return modify(&encryptCfg, func(value string, key *[32]byte) (string, error) {
+						if conf.IsEncrypted(value) {
+							return value, nil
+						}
+						return conf.EncryptValue(value, key)
+					})
+				},
+			},
+			{
+				Name:   "decrypt",
+				Usage:  "decrypt the values of variables",
+				Config: &decryptCfg,
+				Run: func(context.Context, []string) error {
+					return modify(&decryptCfg, func(value string, key *[32]byte) (string, error) {
+						if !conf.IsEncrypted(value) {
+							return value, nil
+						}
+						return conf.DecryptValue(value, key)
+					})
+				},
+			},
+		},
+	}
+
+	if err := cmd.Execute(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// modify replaces the values of the variables in the .env file with the result
+// of fn.
+func modify(cfg *valuesConfig, fn func(value string, key *[32]byte) (string, error)) error {
+	key, err := cfg.key()
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(cfg.File)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return err
+	}
+
+	out, err := rewrite(string(content), cfg.Keys, func(value string) (string, error) {
+		return fn(value, key)
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cfg.File, []byte(out), fi.Mode().Perm())
+}
+
+// rewrite replaces the values of the variables named keys in the content of a
+// .env file with the result of fn, leaving all other lines untouched.
+func rewrite(content string, keys []string, fn func(value string) (string, error)) (string, error) {
+	found := make(map[string]bool)
+
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(trimmed, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !slices.Contains(keys, name) {
+			continue
+		}
+
+		newValue, err := fn(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+
+		lines[i] = name + "=" + newValue
+		if strings.HasSuffix(line, "\n") {
+			lines[i] += "\n"
+		}
+		found[name] = true
+	}
+
+	for _, key := range keys {
+		if !found[key] {
+			return "", fmt.Errorf("%s: not found", key)
+		}
+	}
+
+	return strings.Join(lines, ""), nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewrite(t *testing.T) {
+	content := "# comment\nDB_PASSWORD=hunter2\nAPI_KEY = k3y\nPORT=80\n"
+
+	got, err := rewrite(content, []string{"DB_PASSWORD", "API_KEY"}, func(value string) (string, error) {
+		return strings.ToUpper(value), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# comment\nDB_PASSWORD=HUNTER2\nAPI_KEY=K3Y\nPORT=80\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected content (-want +got):\n%s", diff)
+	}
+
+	if _, err := rewrite(content, []string{"MISSING"}, nil); err == nil || err.Error() != "MISSING: not found" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+// Package example has a configuration struct with a loader generated by
+// confgen, which is compared with conf.Load in its tests.
+package example
+
+//go:generate go run github.com/solhall/conf/cmd/confgen --type Config
+
+type Config struct {
+	Port    int    `conf:"port,default=8080" short:"p" usage:"port to listen on" min:"1" max:"65535"`
+	Verbose bool   `conf:"verbose" short:"v"`
+	Mode    string `conf:"mode,default='fast,safe'"`
+	Level   string `conf:"level,default=info" enum:"debug,info,warn"`
+
+	DB       DB     `conf:"db"`
+	Cache    *Cache `conf:"cache"`
+	Password string `conf:"password,required,file"`
+
+	Server
+
+	File  string   `conf:"file" arg:"0"`
+	Files []string `arg:"rest"`
+
+	// Fields without tags are not loaded.
+	internal string
+}
+
+type DB struct {
+	Host string `conf:"host,required"`
+	Port int    `conf:"port,default=5432"`
+}
+
+type Cache struct {
+	Size    int  `conf:"size"`
+	Enabled bool `conf:"enabled,default=true"`
+}
+
+// Server is embedded, and does not add to the names of its fields.
+type Server struct {
+	Name string `conf:"name,static"`
+}
@@ -0,0 +1,49 @@
+// Code generated by confgen --type Config; DO NOT EDIT.
+
+package example
+
+import (
+	"errors"
+
+	"github.com/solhall/conf"
+)
+
+// LoadConfig loads configuration values into cfg like conf.Load, without
+// reflection.
+func LoadConfig(cfg *Config, opts ...conf.LoadOption) error {
+	if err := conf.LoadFunc(func(p conf.Provider) {
+		p.IntVar(&cfg.Port, "port", 8080, false)
+		p.BoolVar(&cfg.Verbose, "verbose", false, false)
+		p.StringVar(&cfg.Mode, "mode", "fast,safe", false)
+		p.StringVar(&cfg.Level, "level", "info", false)
+		p.StringVar(&cfg.DB.Host, "db.host", "", true)
+		p.IntVar(&cfg.DB.Port, "db.port", 5432, false)
+		if cfg.Cache == nil {
+			cfg.Cache = new(Cache)
+		}
+		p.IntVar(&cfg.Cache.Size, "cache.size", 0, false)
+		p.BoolVar(&cfg.Cache.Enabled, "cache.enabled", true, false)
+		p.StringVar(&cfg.Password, "password", "", true)
+		p.StringVar(&cfg.Server.Name, "name", "", false)
+		if p, ok := p.(conf.ShortFlagProvider); ok {
+			p.ShortVar("port", "p")
+			p.ShortVar("verbose", "v")
+		}
+		if p, ok := p.(conf.TagOptionProvider); ok {
+			p.TagOption("password", "file")
+			p.TagOption("name", "static")
+		}
+		if p, ok := p.(conf.ArgProvider); ok {
+			p.ArgVar(&cfg.File, "file", 0, "", false)
+			p.ArgVar(&cfg.Files, "files", conf.ArgRest, "", false)
+		}
+	}, opts...); err != nil {
+		return err
+	}
+
+	return errors.Join(
+		conf.CheckMin("port", cfg.Port, 1),
+		conf.CheckMax("port", cfg.Port, 65535),
+		conf.CheckEnum("level", cfg.Level, "debug", "info", "warn"),
+	)
+}
@@ -0,0 +1,104 @@
+package example
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{
+			name: "env and flags",
+			env:  map[string]string{"DB_HOST": "db.example.com", "PASSWORD": "hunter2", "CACHE_SIZE": "64"},
+			args: []string{"-p", "80", "-v", "--name=api", "in.txt", "a", "b"},
+		},
+		{
+			name: "missing",
+			env:  map[string]string{"CACHE_ENABLED": "false"},
+		},
+		{
+			name: "invalid flag",
+			env:  map[string]string{"DB_HOST": "db.example.com", "PASSWORD": "hunter2"},
+			args: []string{"--port", "abc"},
+		},
+		{
+			name: "invalid values",
+			env:  map[string]string{"DB_HOST": "db.example.com", "PASSWORD": "hunter2", "LEVEL": "trace"},
+			args: []string{"--port=70000"},
+		},
+		{
+			name: "zero",
+			env:  map[string]string{"DB_HOST": "db.example.com", "PASSWORD": "hunter2"},
+			args: []string{"--port=0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := func() conf.LoadOption {
+				getenv := func(key string) string { return tt.env[key] }
+				return conf.WithProviders(conf.NewEnvProvider(getenv), conf.NewFlagProvider(tt.args))
+			}
+
+			var want Config
+			wantErr := conf.Load(&want, providers())
+
+			var got Config
+			gotErr := LoadConfig(&got, providers())
+
+			if diff := cmp.Diff(errString(wantErr), errString(gotErr)); diff != "" {
+				t.Errorf("unexpected error (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(want, got, cmp.AllowUnexported(Config{})); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestTinyGoDependencies checks that the generated loader builds without the
+// dependencies that package conf leaves out for TinyGo, which sets the tinygo
+// build tag.
+func TestTinyGoDependencies(t *testing.T) {
+	if out, err := exec.Command("go", "build", "-tags", "tinygo", ".").CombinedOutput(); err != nil {
+		t.Fatalf("failed to build with the tinygo tag: %v\n%s", err, out)
+	}
+
+	out, err := exec.Command("go", "list", "-deps", "-tags", "tinygo", ".").Output()
+	if err != nil {
+		t.Fatalf("failed to list dependencies: %v", err)
+	}
+
+	for _, pkg := range strings.Fields(string(out)) {
+		if pkg == "net/http" || pkg == "os/signal" || strings.HasPrefix(pkg, "golang.org/x/crypto/") {
+			t.Errorf("unexpected dependency %s", pkg)
+		}
+	}
+}
+
+// errString returns the message of err, with the missing parameters sorted
+// since their order is random.
+func errString(err error) string {
+	var missing *conf.MissingError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &missing):
+		names := slices.Clone(missing.Names)
+		slices.Sort(names)
+		return (&conf.MissingError{Names: names}).Error()
+	default:
+		return err.Error()
+	}
+}
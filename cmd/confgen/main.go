@@ -0,0 +1,494 @@
+// Command confgen generates a loader for a configuration struct that registers
+// its fields with the providers without reflection, for go generate:
+//
+//	//go:generate go run github.com/solhall/conf/cmd/confgen --type Config
+//
+// For the type Config, it writes the function LoadConfig to config_conf.go:
+//
+//	func LoadConfig(cfg *Config, opts ...conf.LoadOption) error
+//
+// LoadConfig behaves like conf.Load, with the restrictions of conf.LoadFunc,
+// and builds with TinyGo.
+// The `conf` tags are checked when generating, so that they cannot fail at
+// run time, and the `enum`, `min` and `max` tags become calls to
+// conf.CheckEnum, conf.CheckMin and conf.CheckMax after loading. Nested
+// structs must be declared in the same package.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/solhall/conf"
+	"github.com/solhall/conf/internal/structtag"
+)
+
+type config struct {
+	Type   string `conf:"type,required" short:"t" usage:"name of the configuration struct"`
+	Func   string `conf:"func" usage:"name of the generated function, Load<type> by default"`
+	Output string `conf:"output" short:"o" usage:"output file, <type>_conf.go by default"`
+	Dir    string `conf:"dir,default=." usage:"directory of the package"`
+}
+
+func main() {
+	var cfg config
+	if err := conf.LoadFlags(&cfg, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "confgen:", err)
+		os.Exit(2)
+	}
+
+	if cfg.Func == "" {
+		cfg.Func = "Load" + cfg.Type
+	}
+	if cfg.Output == "" {
+		cfg.Output = strings.ToLower(cfg.Type) + "_conf.go"
+	}
+
+	src, err := generate(cfg.Dir, cfg.Type, cfg.Func, cfg.Output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "confgen:", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.Dir, cfg.Output), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "confgen:", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the source of the loader funcName for the struct typeName
+// in the package in dir. The file output is not read, since it is replaced.
+func generate(dir, typeName, funcName, output string) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		fset:       token.NewFileSet(),
+		types:      make(map[string]ast.Expr),
+		shortNames: make(map[string]string),
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || filepath.Base(file) == output {
+			continue
+		}
+
+		f, err := parser.ParseFile(g.fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		g.pkg = f.Name.Name
+
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				g.types[spec.Name.Name] = spec.Type
+			}
+		}
+	}
+
+	st, ok := g.types[typeName].(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("struct type %s not found in %s", typeName, dir)
+	}
+
+	if err := g.walkStruct(st, "cfg", "", nil, true); err != nil {
+		return nil, fmt.Errorf("%s: %w", typeName, err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by confgen --type %s; DO NOT EDIT.\n\n", typeName)
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+	if len(g.checks) > 0 {
+		fmt.Fprintf(&b, "import (\n\"errors\"\n\n\"github.com/solhall/conf\"\n)\n\n")
+	} else {
+		fmt.Fprintf(&b, "import \"github.com/solhall/conf\"\n\n")
+	}
+	fmt.Fprintf(&b, "// %s loads configuration values into cfg like conf.Load, without\n// reflection.\n", funcName)
+	fmt.Fprintf(&b, "func %s(cfg *%s, opts ...conf.LoadOption) error {\n", funcName, typeName)
+	if len(g.checks) > 0 {
+		fmt.Fprintf(&b, "if err := conf.LoadFunc(func(p conf.Provider) {\n")
+	} else {
+		fmt.Fprintf(&b, "return conf.LoadFunc(func(p conf.Provider) {\n")
+	}
+	for _, line := range g.vars {
+		fmt.Fprintln(&b, line)
+	}
+	g.writeBlock(&b, "ShortFlagProvider", g.shorts)
+	g.writeBlock(&b, "TagOptionProvider", g.options)
+	g.writeBlock(&b, "ArgProvider", g.args)
+	if len(g.checks) > 0 {
+		fmt.Fprintf(&b, "}, opts...); err != nil {\nreturn err\n}\n\nreturn errors.Join(\n")
+		for _, check := range g.checks {
+			fmt.Fprintf(&b, "%s,\n", check)
+		}
+		fmt.Fprintf(&b, ")\n}\n")
+	} else {
+		fmt.Fprintf(&b, "}, opts...)\n}\n")
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// generator collects the statements of the loader.
+type generator struct {
+	fset *token.FileSet
+	pkg  string
+	// types are the types declared in the package by name.
+	types map[string]ast.Expr
+	// shortNames maps the short names to the parameters using them.
+	shortNames map[string]string
+
+	// vars allocate the pointers to structs and register the parameters,
+	// shorts, options and args call the optional provider interfaces.
+	vars    []string
+	shorts  []string
+	options []string
+	args    []string
+	// checks validate the loaded values.
+	checks []string
+}
+
+// writeBlock writes the statements calling the optional provider interface
+// iface, if there are any.
+func (g *generator) writeBlock(b *bytes.Buffer, iface string, stmts []string) {
+	if len(stmts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "if p, ok := p.(conf.%s); ok {\n", iface)
+	for _, stmt := range stmts {
+		fmt.Fprintln(b, stmt)
+	}
+	fmt.Fprintf(b, "}\n")
+}
+
+// walkStruct adds the fields of the struct st, which is accessed by the
+// expression path and nested in the pointers to the named structs parents.
+// settable is whether the exported fields of st can be set, like in conf.
+func (g *generator) walkStruct(st *ast.StructType, path, prefix string, parents []string, settable bool) error {
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			raw, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(raw)
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(embeddedName(field.Type))}
+		}
+
+		for _, name := range names {
+			f := astField{
+				name:     name.Name,
+				typ:      field.Type,
+				tag:      tag,
+				embedded: len(field.Names) == 0,
+			}
+			if err := g.walkField(f, path+"."+f.name, prefix, parents, settable); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// astField is a field of a struct in the source.
+type astField struct {
+	name     string
+	typ      ast.Expr
+	tag      reflect.StructTag
+	embedded bool
+}
+
+func (g *generator) walkField(f astField, path, prefix string, parents []string, settable bool) error {
+	if st, ptr, typeName := g.structType(f); st != nil {
+		if _, err := structtag.Parse(f.tag.Get("conf")); err != nil {
+			return fmt.Errorf("field %s: invalid conf tag: %w", f.name, err)
+		}
+
+		if ptr {
+			if typeName != "" && slices.Contains(parents, typeName) {
+				return fmt.Errorf("field %s: recursive type *%s", f.name, typeName)
+			}
+			parents = append(slices.Clone(parents), typeName)
+
+			if !settable || !ast.IsExported(f.name) {
+				return fmt.Errorf("field %s: cannot allocate unexported pointer to struct", f.name)
+			}
+
+			elem := g.exprString(f.typ.(*ast.StarExpr).X)
+			g.vars = append(g.vars, fmt.Sprintf("if %s == nil {\n%s = new(%s)\n}", path, path, elem))
+		}
+
+		// The exported fields of embedded structs can be set even if the
+		// struct type is unexported.
+		settable = settable && (ast.IsExported(f.name) || f.embedded)
+
+		return g.walkStruct(st, path, structPrefix(prefix, f), parents, settable)
+	}
+
+	arg, hasArg := f.tag.Lookup("arg")
+	if (hasArg || f.tag.Get("conf") != "") && !(settable && ast.IsExported(f.name)) {
+		return fmt.Errorf("field %s: cannot load unexported field, export it or remove its tags", f.name)
+	}
+
+	if hasArg {
+		return g.walkArg(f, path, prefix, arg)
+	}
+
+	tagVal := f.tag.Get("conf")
+	if tagVal == "" {
+		return nil
+	}
+
+	tag, err := structtag.Parse(tagVal)
+	if err != nil {
+		return fmt.Errorf("field %s: invalid conf tag: %w", f.name, err)
+	}
+	name := prefix + tag.Name
+
+	kind, err := g.kind(f)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(tag.Options, "count") && kind != reflect.Int {
+		return fmt.Errorf("field %s: option \"count\" needs an int field, got %s", f.name, kind)
+	}
+	if slices.Contains(tag.Options, "list") && kind != reflect.String {
+		return fmt.Errorf("field %s: option \"list\" needs a string field, got %s", f.name, kind)
+	}
+
+	switch kind {
+	case reflect.Int:
+		var fallback int
+		if tag.Fallback != "" {
+			if fallback, err = strconv.Atoi(tag.Fallback); err != nil {
+				return fmt.Errorf("failed to parse fallback value %q as int: %w", tag.Fallback, err)
+			}
+		}
+		g.vars = append(g.vars, fmt.Sprintf("p.IntVar(&%s, %q, %d, %t)", path, name, fallback, tag.Required))
+	case reflect.String:
+		g.vars = append(g.vars, fmt.Sprintf("p.StringVar(&%s, %q, %q, %t)", path, name, tag.Fallback, tag.Required))
+	case reflect.Bool:
+		var fallback bool
+		if tag.Fallback != "" {
+			if fallback, err = strconv.ParseBool(tag.Fallback); err != nil {
+				return fmt.Errorf("failed to parse fallback value %q as bool: %w", tag.Fallback, err)
+			}
+		}
+		g.vars = append(g.vars, fmt.Sprintf("p.BoolVar(&%s, %q, %t, %t)", path, name, fallback, tag.Required))
+	default:
+		return nil
+	}
+
+	if short := f.tag.Get("short"); short != "" {
+		if len(short) != 1 {
+			return fmt.Errorf("short name %q of %s must be a single character", short, name)
+		}
+		if other, ok := g.shortNames[short]; ok {
+			return fmt.Errorf("short name %q of %s is already used by %s", short, name, other)
+		}
+		g.shortNames[short] = name
+		g.shorts = append(g.shorts, fmt.Sprintf("p.ShortVar(%q, %q)", name, short))
+	}
+
+	for _, option := range tag.Options {
+		g.options = append(g.options, fmt.Sprintf("p.TagOption(%q, %q)", name, option))
+	}
+
+	return g.addChecks(f, path, name, kind, tag)
+}
+
+// addChecks adds the checks of the `enum`, `min` and `max` tags of a field,
+// like conf.Load does after loading. Like conf.Load, it requires a default or
+// the required option for fields with these tags.
+func (g *generator) addChecks(f astField, path, name string, kind reflect.Kind, tag structtag.Tag) error {
+	var first string
+	if enum, ok := f.tag.Lookup("enum"); ok {
+		first = "enum"
+		if kind != reflect.String {
+			return fmt.Errorf("field %s: enum tag on %s field, only string fields are supported", f.name, kind)
+		}
+
+		values := strings.Split(enum, ",")
+		for i, value := range values {
+			values[i] = strconv.Quote(value)
+		}
+		g.checks = append(g.checks, fmt.Sprintf("conf.CheckEnum(%q, %s, %s)", name, path, strings.Join(values, ", ")))
+	}
+
+	for _, bound := range []struct{ tag, check string }{{"min", "CheckMin"}, {"max", "CheckMax"}} {
+		raw, ok := f.tag.Lookup(bound.tag)
+		if !ok {
+			continue
+		}
+		if kind != reflect.Int {
+			return fmt.Errorf("field %s: %s tag on %s field, only int fields are supported", f.name, bound.tag, kind)
+		}
+
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("field %s: invalid %s tag %q: %w", f.name, bound.tag, raw, err)
+		}
+		g.checks = append(g.checks, fmt.Sprintf("conf.%s(%q, %s, %d)", bound.check, name, path, n))
+		if first == "" {
+			first = bound.tag
+		}
+	}
+
+	if first != "" && !tag.Required && tag.Fallback == "" {
+		return fmt.Errorf("field %s: %s tag needs a default or the required option", f.name, first)
+	}
+
+	return nil
+}
+
+// walkArg adds a field tagged with `arg` as a positional argument.
+func (g *generator) walkArg(f astField, path, prefix, arg string) error {
+	tag, err := structtag.Parse(f.tag.Get("conf"))
+	if err != nil {
+		return fmt.Errorf("field %s: invalid conf tag: %w", f.name, err)
+	}
+
+	name := tag.Name
+	if name == "" {
+		name = strings.ToLower(f.name)
+	}
+
+	index := "conf.ArgRest"
+	if arg == "rest" {
+		if g.exprString(f.typ) != "[]string" {
+			return fmt.Errorf("field %s: rest arguments must be a []string, got %s", f.name, g.exprString(f.typ))
+		}
+	} else {
+		i, err := strconv.Atoi(arg)
+		if err != nil || i < 0 {
+			return fmt.Errorf("field %s: invalid arg tag %q, expected a position or \"rest\"", f.name, arg)
+		}
+		index = strconv.Itoa(i)
+
+		kind, err := g.kind(f)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case reflect.String, reflect.Int, reflect.Bool:
+		default:
+			return fmt.Errorf("field %s: positional argument has unsupported type %s", f.name, g.exprString(f.typ))
+		}
+	}
+
+	g.args = append(g.args, fmt.Sprintf("p.ArgVar(&%s, %q, %s, %q, %t)", path, prefix+name, index, tag.Fallback, tag.Required))
+
+	return nil
+}
+
+// structType returns the struct type of a field whose fields are loaded like
+// those of the configuration struct, whether it is a pointer and the name of
+// the struct type if it is named.
+func (g *generator) structType(f astField) (st *ast.StructType, ptr bool, name string) {
+	typ := f.typ
+	if star, ok := typ.(*ast.StarExpr); ok {
+		// Like in conf, pointers are only followed if they are embedded
+		// or have a `conf` tag.
+		if !f.embedded && f.tag.Get("conf") == "" {
+			return nil, false, ""
+		}
+		typ, ptr = star.X, true
+	}
+
+	if ident, ok := typ.(*ast.Ident); ok {
+		name = ident.Name
+		typ = g.types[ident.Name]
+	}
+
+	st, _ = typ.(*ast.StructType)
+	return st, ptr, name
+}
+
+// kind returns the kind of a field that is not a struct. Types declared in
+// other packages cannot be resolved, and are only allowed for fields that are
+// not loaded.
+func (g *generator) kind(f astField) (reflect.Kind, error) {
+	switch typ := f.typ.(type) {
+	case *ast.Ident:
+		switch typ.Name {
+		case "string":
+			return reflect.String, nil
+		case "int":
+			return reflect.Int, nil
+		case "bool":
+			return reflect.Bool, nil
+		}
+
+		if _, ok := g.types[typ.Name]; ok {
+			return reflect.Invalid, fmt.Errorf("field %s: unsupported type %s, use string, int or bool", f.name, typ.Name)
+		}
+
+		// Other predeclared types are not loaded, like in conf.
+		return reflect.Invalid, nil
+	case *ast.SelectorExpr:
+		return reflect.Invalid, fmt.Errorf("field %s: unsupported type %s, use string, int or bool", f.name, g.exprString(typ))
+	default:
+		return reflect.Invalid, nil
+	}
+}
+
+func (g *generator) exprString(expr ast.Expr) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, g.fset, expr); err != nil {
+		panic(err)
+	}
+
+	return b.String()
+}
+
+// structPrefix returns the prefix for the fields of a nested struct, like
+// in conf.
+func structPrefix(prefix string, f astField) string {
+	tag, _ := structtag.Parse(f.tag.Get("conf"))
+	if tag.Name == "" {
+		return prefix
+	}
+
+	return prefix + tag.Name + "."
+}
+
+// embeddedName returns the field name of an embedded type.
+func embeddedName(typ ast.Expr) string {
+	switch typ := typ.(type) {
+	case *ast.StarExpr:
+		return embeddedName(typ.X)
+	case *ast.SelectorExpr:
+		return typ.Sel.Name
+	case *ast.Ident:
+		return typ.Name
+	case *ast.IndexExpr:
+		return embeddedName(typ.X)
+	default:
+		panic(fmt.Sprintf("unexpected embedded type %T", typ))
+	}
+}
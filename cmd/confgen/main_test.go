@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+
+	got, err := generate(dir, "Config", "LoadConfig", "config_conf.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want, err := os.ReadFile(filepath.Join(dir, "config_conf.go"))
+	if err != nil {
+		t.Fatalf("failed to read generated file: %v", err)
+	}
+
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("generated file is out of date, run go generate (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "misspelled option",
+			src:  "type Config struct {\n\tHost string `conf:\"host,requried\"`\n}",
+			want: `Config: field Host: invalid conf tag: unknown option "requried", did you mean "required"?`,
+		},
+		{
+			name: "invalid fallback",
+			src:  "type Config struct {\n\tPort int `conf:\"port,default=abc\"`\n}",
+			want: `Config: failed to parse fallback value "abc" as int: strconv.Atoi: parsing "abc": invalid syntax`,
+		},
+		{
+			name: "unexported",
+			src:  "type Config struct {\n\tport int `conf:\"port\"`\n}",
+			want: "Config: field port: cannot load unexported field, export it or remove its tags",
+		},
+		{
+			name: "recursive",
+			src:  "type Config struct {\n\tRoot *Node `conf:\"root\"`\n}\n\ntype Node struct {\n\tNext *Node `conf:\"next\"`\n}",
+			want: "Config: field Next: recursive type *Node",
+		},
+		{
+			name: "other package",
+			src:  "import \"time\"\n\ntype Config struct {\n\tTimeout time.Duration `conf:\"timeout\"`\n}",
+			want: "Config: field Timeout: unsupported type time.Duration, use string, int or bool",
+		},
+		{
+			name: "count option on string",
+			src:  "type Config struct {\n\tName string `conf:\"name,count\"`\n}",
+			want: `Config: field Name: option "count" needs an int field, got string`,
+		},
+		{
+			name: "list option on int",
+			src:  "type Config struct {\n\tPort int `conf:\"port,list\"`\n}",
+			want: `Config: field Port: option "list" needs a string field, got int`,
+		},
+		{
+			name: "duplicate short",
+			src:  "type Config struct {\n\tPort int `conf:\"port\" short:\"p\"`\n\tPath string `conf:\"path\" short:\"p\"`\n}",
+			want: `Config: short name "p" of path is already used by port`,
+		},
+		{
+			name: "invalid bound",
+			src:  "type Config struct {\n\tPort int `conf:\"port\" min:\"one\"`\n}",
+			want: `Config: field Port: invalid min tag "one": strconv.Atoi: parsing "one": invalid syntax`,
+		},
+		{
+			name: "bound without default",
+			src:  "type Config struct {\n\tPort int `conf:\"port\" max:\"65535\"`\n}",
+			want: "Config: field Port: max tag needs a default or the required option",
+		},
+		{
+			name: "enum on int",
+			src:  "type Config struct {\n\tPort int `conf:\"port\" enum:\"1,2\"`\n}",
+			want: "Config: field Port: enum tag on int field, only string fields are supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte("package example\n\n"+tt.src+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := generate(dir, "Config", "LoadConfig", "config_conf.go")
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := generate(dir, "Config", "LoadConfig", "config_conf.go")
+		if err == nil || err.Error() != "struct type Config not found in "+dir {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
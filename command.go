@@ -0,0 +1,232 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command is a command line program or one of its subcommands. Each command
+// has its own configuration struct, which is loaded from the providers and
+// the flags given before its subcommand, if any:
+//
+//	tool --verbose serve --port 80 ./public
+//
+// loads --verbose into the config of tool and --port into the config of
+// serve, and calls the Run function of serve with the remaining "./public".
+type Command struct {
+	// Name is the name of the command, used to select it as a subcommand.
+	Name string
+	// Usage is a short description of the command shown in its help.
+	Usage string
+	// Config is a pointer to the struct the configuration of the command is
+	// loaded into. It can be nil if the command has no configuration.
+	Config any
+	// Run is called with the arguments left after the flags. It can be nil
+	// for commands that only group subcommands.
+	Run func(ctx context.Context, args []string) error
+	// Commands are the subcommands of the command.
+	Commands []*Command
+
+	// Providers returns the providers used to load the configuration, in
+	// addition to the flags, which take the highest priority. It is called
+	// once for every command that is executed and is inherited by the
+	// subcommands. If nil, the environment is used.
+	Providers func() []Provider
+	// Output is where help is written to. It is inherited by the
+	// subcommands. If nil, os.Stderr is used.
+	Output io.Writer
+
+	parent *Command
+}
+
+// Execute parses args, loads the configuration of the command and its
+// selected subcommands and runs the last one. args should not include the
+// program name, e.g. os.Args[1:].
+//
+// If -h, --help or the help subcommand is given, the help of the command is
+// written to Output and nil is returned.
+func (c *Command) Execute(ctx context.Context, args []string) error {
+	var remaining []string
+
+	cfg := c.Config
+	if cfg == nil {
+		cfg = &struct{}{}
+	}
+
+	providers := append(c.providers(), NewFlagProvider(args).WithRemainingFunc(func(r []string) {
+		remaining = r
+	}))
+	if err := Load(cfg, WithProviders(providers...)); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return c.Help()
+		}
+
+		return fmt.Errorf("%s: %w", c.path(), err)
+	}
+
+	if len(c.Commands) > 0 && len(remaining) > 0 {
+		name := remaining[0]
+		if name == "help" {
+			if len(remaining) > 1 {
+				if sub := c.command(remaining[1]); sub != nil {
+					return sub.Help()
+				}
+			}
+
+			return c.Help()
+		}
+
+		sub := c.command(name)
+		if sub == nil {
+			return fmt.Errorf("%s: unknown command %q", c.path(), name)
+		}
+
+		return sub.Execute(ctx, remaining[1:])
+	}
+
+	if c.Run == nil {
+		if err := c.Help(); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("%s: no command given", c.path())
+	}
+
+	return c.Run(ctx, remaining)
+}
+
+// Help writes the generated help of the command to Output.
+func (c *Command) Help() error {
+	var b strings.Builder
+
+	var fields, flags []schemaField
+	if c.Config != nil {
+		s, err := compileConfig(c.Config)
+		if err != nil {
+			return err
+		}
+		fields, flags = s.fields, s.options()
+	}
+
+	fmt.Fprintf(&b, "Usage: %s", c.path())
+	if c.Config != nil {
+		b.WriteString(" [flags]")
+	}
+	if len(c.Commands) > 0 {
+		b.WriteString(" <command> [args]")
+	} else {
+		b.WriteString(argsUsage(fields))
+	}
+	b.WriteString("\n")
+
+	if c.Usage != "" {
+		fmt.Fprintf(&b, "\n%s\n", c.Usage)
+	}
+
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	if len(c.Commands) > 0 {
+		fmt.Fprintf(tw, "\nCommands:\n")
+		for _, sub := range c.Commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Usage)
+		}
+	}
+
+	if len(flags) > 0 {
+		fmt.Fprintf(tw, "\nFlags:\n")
+		for _, f := range flags {
+			names := "--" + strings.ReplaceAll(f.name, "_", "-")
+			if f.short != "" {
+				names = "-" + f.short + ", " + names
+			}
+
+			usage := f.usage
+			switch {
+			case f.required:
+				usage += " (required)"
+			case f.fallback != "":
+				usage += " (default " + f.fallback + ")"
+			}
+
+			fmt.Fprintf(tw, "  %s %s\t%s\n", names, f.kind, strings.TrimSpace(usage))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(c.output(), b.String())
+	return err
+}
+
+// argsUsage returns the positional arguments for the usage line, e.g.
+// " <src> [dst] [files...]". Without positional arguments, " [args]" is
+// returned.
+func argsUsage(fields []schemaField) string {
+	var b strings.Builder
+	for _, f := range fields {
+		switch {
+		case !f.arg:
+			continue
+		case f.argIndex == ArgRest:
+			fmt.Fprintf(&b, " [%s...]", f.name)
+		case f.required:
+			fmt.Fprintf(&b, " <%s>", f.name)
+		default:
+			fmt.Fprintf(&b, " [%s]", f.name)
+		}
+	}
+
+	if b.Len() == 0 {
+		return " [args]"
+	}
+
+	return b.String()
+}
+
+// command returns the subcommand with the given name, or nil.
+func (c *Command) command(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			sub.parent = c
+			return sub
+		}
+	}
+
+	return nil
+}
+
+// path returns the names of the command and its parents, e.g. "tool serve".
+func (c *Command) path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+
+	return c.parent.path() + " " + c.Name
+}
+
+func (c *Command) providers() []Provider {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.Providers != nil {
+			return cmd.Providers()
+		}
+	}
+
+	return []Provider{NewEnvProvider(os.Getenv)}
+}
+
+func (c *Command) output() io.Writer {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.Output != nil {
+			return cmd.Output
+		}
+	}
+
+	return os.Stderr
+}
@@ -0,0 +1,163 @@
+package conf_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommand(t *testing.T) {
+	type globalConfig struct {
+		Verbose bool `conf:"verbose" short:"v" usage:"enable verbose output"`
+	}
+
+	type serveConfig struct {
+		Port int    `conf:"port,default=8080" short:"p" usage:"port to listen on"`
+		Host string `conf:"host,required" usage:"host to listen on"`
+	}
+
+	newCommand := func(env env, out *strings.Builder, global *globalConfig, serve *serveConfig, args *[]string) *conf.Command {
+		return &conf.Command{
+			Name:   "tool",
+			Usage:  "tool does things.",
+			Config: global,
+			Commands: []*conf.Command{
+				{
+					Name:   "serve",
+					Usage:  "serve files",
+					Config: serve,
+					Run: func(_ context.Context, a []string) error {
+						*args = a
+						return nil
+					},
+				},
+				{
+					Name:  "version",
+					Usage: "print the version",
+					Run: func(context.Context, []string) error {
+						return nil
+					},
+				},
+			},
+			Providers: func() []conf.Provider {
+				return []conf.Provider{conf.NewEnvProvider(env.Get)}
+			},
+			Output: out,
+		}
+	}
+
+	t.Run("dispatch", func(t *testing.T) {
+		var (
+			out    strings.Builder
+			global globalConfig
+			serve  serveConfig
+			args   []string
+		)
+		cmd := newCommand(env{"HOST": "example.com"}, &out, &global, &serve, &args)
+
+		if err := cmd.Execute(context.Background(), []string{"-v", "serve", "-p", "80", "./public"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !global.Verbose {
+			t.Errorf("expected verbose to be set")
+		}
+
+		if diff := cmp.Diff(serveConfig{Port: 80, Host: "example.com"}, serve); diff != "" {
+			t.Errorf("unexpected serve config (-want +got):\n%s", diff)
+		}
+
+		if diff := cmp.Diff([]string{"./public"}, args); diff != "" {
+			t.Errorf("unexpected args (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		var (
+			out    strings.Builder
+			global globalConfig
+			serve  serveConfig
+			args   []string
+		)
+		cmd := newCommand(env{}, &out, &global, &serve, &args)
+
+		err := cmd.Execute(context.Background(), []string{"unknown"})
+		if err == nil || err.Error() != `tool: unknown command "unknown"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var (
+			out    strings.Builder
+			global globalConfig
+			serve  serveConfig
+			args   []string
+		)
+		cmd := newCommand(env{}, &out, &global, &serve, &args)
+
+		err := cmd.Execute(context.Background(), []string{"serve"})
+		if err == nil || err.Error() != "tool serve: missing configuration parameters: host" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var (
+			out    strings.Builder
+			global globalConfig
+			serve  serveConfig
+			args   []string
+		)
+		cmd := newCommand(env{}, &out, &global, &serve, &args)
+
+		if err := cmd.Execute(context.Background(), []string{"--help"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := `Usage: tool [flags] <command> [args]
+
+tool does things.
+
+Commands:
+  serve    serve files
+  version  print the version
+
+Flags:
+  -v, --verbose bool  enable verbose output
+`
+		if diff := cmp.Diff(want, out.String()); diff != "" {
+			t.Errorf("unexpected help (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("subcommand help", func(t *testing.T) {
+		var (
+			out    strings.Builder
+			global globalConfig
+			serve  serveConfig
+			args   []string
+		)
+		cmd := newCommand(env{}, &out, &global, &serve, &args)
+
+		if err := cmd.Execute(context.Background(), []string{"serve", "-h"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := `Usage: tool serve [flags] [args]
+
+serve files
+
+Flags:
+  -p, --port int  port to listen on (default 8080)
+  --host string   host to listen on (required)
+`
+		if diff := cmp.Diff(want, out.String()); diff != "" {
+			t.Errorf("unexpected help (-want +got):\n%s", diff)
+		}
+	})
+}
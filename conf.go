@@ -1,16 +1,30 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
-	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/solhall/conf/internal/structtag"
+)
+
+const (
+	tagName      = "conf"
+	shortTagName = "short"
+	argTagName   = "arg"
 )
 
-const tagName = "conf"
+// ArgRest is the index passed to ArgProvider.ArgVar for a field tagged
+// `arg:"rest"`.
+const ArgRest = -1
 
+// Provider is a source of configuration values. A provider can be loaded more
+// than once, e.g. by Watch; registering a name again replaces the previous
+// registration.
 type Provider interface {
 	// StringVar registers a pointer to a string that will be set to the value of
 	// the configuration parameter with the given name. If the parameter is
@@ -28,6 +42,46 @@ type Provider interface {
 	Missing() []string
 }
 
+// FileSource is implemented by providers that read files, so that Watch can
+// reload the configuration when they change.
+type FileSource interface {
+	// Files returns the paths of the files read by Load.
+	Files() []string
+}
+
+// ShortFlagProvider is implemented by providers that support single letter
+// aliases for configuration parameters, set with the `short` struct tag, e.g.
+// `conf:"port" short:"p"`.
+type ShortFlagProvider interface {
+	Provider
+	// ShortVar registers short as an alias for the parameter with the given
+	// name. It must be called after the parameter has been registered.
+	ShortVar(name, short string)
+}
+
+// TagOptionProvider is implemented by providers that support options in the
+// `conf` struct tag beyond "required" and "default", e.g. "file" in
+// `conf:"db_password,file"`.
+type TagOptionProvider interface {
+	Provider
+	// TagOption sets an option of the parameter with the given name. It
+	// must be called after the parameter has been registered. Unknown
+	// options are ignored.
+	TagOption(name, option string)
+}
+
+// ArgProvider is implemented by providers that bind positional arguments to
+// fields with the `arg` struct tag, e.g. `arg:"0"` for the first argument or
+// `arg:"rest"` for all arguments after the numbered ones.
+type ArgProvider interface {
+	Provider
+	// ArgVar registers a pointer that will be set to the positional argument
+	// at index. to is a *string, *int or *bool, or a *[]string if index is
+	// ArgRest. If the argument is not given, the fallback value will be
+	// used, and if it is required, it will be considered missing.
+	ArgVar(to any, name string, index int, fallback string, required bool)
+}
+
 // LoadFlags is a shorthand for using Load with the FlagProvider.
 func LoadFlags(cfg any, args []string) error {
 	return Load(cfg, WithProviders(NewFlagProvider(args)))
@@ -47,6 +101,25 @@ type loadConfig struct {
 	provider Provider
 
 	remainingArgs *[]string
+
+	// defaults is a struct of the type cfg points to, or nil.
+	defaults any
+
+	// nilStructs makes Load reset the pointers to structs it allocated to
+	// nil if their structs are still zero after loading.
+	nilStructs bool
+
+	// decrypt decrypts the values of a struct, see WithDecryptionKey. It is
+	// a function so that only encrypt.go depends on the cipher.
+	decrypt func(v reflect.Value, fields []schemaField) error
+	secrets *Secrets
+
+	// The following are only used by Watch.
+	watchFiles    []string
+	watchInterval time.Duration
+	reloadSignals []os.Signal
+	onChange      func(changes []Change)
+	onError       func(err error)
 }
 
 type LoadOption func(*loadConfig)
@@ -61,6 +134,27 @@ func WithProviders(providers ...Provider) LoadOption {
 	}
 }
 
+// WithDefaults returns a LoadOption that starts from a copy of defaults, a
+// struct or pointer to a struct of the same type as cfg. Every field that no
+// provider sets keeps its value from defaults, including fields without a
+// `conf` tag. The non-zero values of tagged fields take precedence over the
+// `default=` of their tag. Nested pointers to structs are copied as well, so
+// defaults is never modified by loading.
+func WithDefaults(defaults any) LoadOption {
+	return func(c *loadConfig) {
+		c.defaults = defaults
+	}
+}
+
+// WithNilStructs returns a LoadOption that leaves fields that are pointers to
+// structs nil if none of the fields of their struct is set. Without it, Load
+// allocates every nil pointer to a struct.
+func WithNilStructs() LoadOption {
+	return func(c *loadConfig) {
+		c.nilStructs = true
+	}
+}
+
 // LoadAll is a shorthand for using Load with all available providers.
 func LoadAll(cfg any) error {
 	return Load(cfg, WithProviders(
@@ -74,106 +168,133 @@ func LoadAll(cfg any) error {
 // If no providers are given via the LoadOptions, the default provider is the
 // environment, using os.Getenv.
 func Load(cfg any, opts ...LoadOption) error {
-	t := reflect.TypeOf(cfg)
-	// cfg must be a pointer to a struct
-	switch t.Kind() {
-	case reflect.Ptr:
-		t = t.Elem()
-		if t.Kind() != reflect.Struct {
-			return fmt.Errorf("expected pointer to struct, got pointer to %s", t.Kind())
-		}
-	default:
-		return fmt.Errorf("expected pointer to struct, got %s", t.Kind())
+	t, err := structType(cfg)
+	if err != nil {
+		return err
 	}
 
-	c := &loadConfig{
-		provider: NewEnvProvider(os.Getenv),
+	s, err := compileStruct(t, "")
+	if err != nil {
+		return err
 	}
 
-	for _, opt := range opts {
-		opt(c)
-	}
+	return s.load(reflect.ValueOf(cfg).Elem(), newLoadConfig(opts))
+}
 
-	v := reflect.ValueOf(cfg).Elem()
-	for i := 0; i < t.NumField(); i++ {
-		if err := c.LoadField(t.Field(i), v.Field(i)); err != nil {
-			return err
-		}
+// LoadFunc loads configuration like Load, but the parameters are registered
+// with the provider by register instead of from the fields of a struct. It is
+// used by the loaders generated by cmd/confgen, which do not use reflection.
+//
+// WithDefaults, WithNilStructs, WithDecryptionKey, WithKeyFile and WithSecrets
+// need reflection, and are not supported.
+//
+// With TinyGo, which sets the tinygo build tag, the package leaves out
+// HTTPProvider, Watch and decryption, so that it does not depend on net/http,
+// os/signal and golang.org/x/crypto.
+func LoadFunc(register func(p Provider), opts ...LoadOption) error {
+	c := newLoadConfig(opts)
+	if c.defaults != nil || c.nilStructs || c.decrypt != nil || c.secrets != nil {
+		return errors.New("LoadFunc does not support defaults, nil structs, decryption and secrets")
 	}
 
+	register(c.provider)
+
 	if err := c.provider.Load(); err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	if missing := c.provider.Missing(); len(missing) > 0 {
-		return fmt.Errorf("missing configuration parameters: %s", strings.Join(missing, ", "))
+		return &MissingError{Names: missing}
 	}
 
 	return nil
 }
 
-func (c *loadConfig) LoadField(field reflect.StructField, value reflect.Value) error {
-	// if field is embedded struct, recursively load it
-	if field.Type.Kind() == reflect.Struct {
-		for i := 0; i < field.Type.NumField(); i++ {
-			if err := c.LoadField(field.Type.Field(i), value.Field(i)); err != nil {
-				return err
-			}
-		}
-		return nil
+// newLoadConfig applies opts to the default configuration.
+func newLoadConfig(opts []LoadOption) *loadConfig {
+	c := &loadConfig{
+		provider: NewEnvProvider(os.Getenv),
 	}
 
-	// e.g. "field1,default=my value,required"
-	tagVal := field.Tag.Get(tagName)
-	if tagVal == "" {
-		return nil
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	tagVal, required, fallback := parseTag(tagVal)
+	return c
+}
 
-	switch field.Type.Kind() {
-	case reflect.Int:
-		var fallbackInt int
-		if fallback != "" {
-			var err error
-			fallbackInt, err = strconv.Atoi(fallback)
-			if err != nil {
-				return fmt.Errorf("failed to parse fallback value %q as int: %w", fallback, err)
-			}
-		}
-		c.provider.IntVar(value.Addr().Interface().(*int), tagVal, fallbackInt, required)
-	case reflect.String:
-		c.provider.StringVar(value.Addr().Interface().(*string), tagVal, fallback, required)
-	case reflect.Bool:
-		var fallbackBool bool
-		if fallback != "" {
-			var err error
-			fallbackBool, err = strconv.ParseBool(fallback)
-			if err != nil {
-				return fmt.Errorf("failed to parse fallback value %q as bool: %w", fallback, err)
-			}
+// MissingError is returned by Load if required parameters have no value.
+type MissingError struct {
+	// Names are the names of the missing parameters.
+	Names []string
+}
+
+func (e *MissingError) Error() string {
+	return "missing configuration parameters: " + strings.Join(e.Names, ", ")
+}
+
+// structType returns the struct type cfg points to.
+func structType(cfg any) (reflect.Type, error) {
+	t := reflect.TypeOf(cfg)
+	if t == nil {
+		return nil, fmt.Errorf("expected pointer to struct, got nil")
+	}
+
+	// cfg must be a pointer to a struct
+	switch t.Kind() {
+	case reflect.Ptr:
+		t = t.Elem()
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("expected pointer to struct, got pointer to %s", t.Kind())
 		}
-		c.provider.BoolVar(value.Addr().Interface().(*bool), tagVal, fallbackBool, required)
+	default:
+		return nil, fmt.Errorf("expected pointer to struct, got %s", t.Kind())
 	}
 
-	return nil
+	return t, nil
 }
 
-func parseTag(tag string) (name string, required bool, fallback string) {
-	parts := strings.Split(tag, ",")
-	name = parts[0]
+// isNestedStruct reports whether the fields of the struct in field are loaded
+// like those of the configuration struct. This is the case for structs, and
+// for pointers to structs that are embedded or have a `conf` tag, which are
+// allocated if nil.
+func isNestedStruct(field reflect.StructField) bool {
+	switch {
+	case field.Type.Kind() == reflect.Struct:
+		return true
+	case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct:
+		return field.Anonymous || field.Tag.Get(tagName) != ""
+	default:
+		return false
+	}
+}
 
-	if slices.Contains(parts, "required") {
-		required = true
+// structPrefix returns the prefix for the fields of a nested struct. If the
+// struct field has a name in its `conf` tag, its fields are namespaced with
+// it, e.g. "db.host" for the field "host" in the struct field "db". Untagged
+// and embedded structs do not add to the prefix.
+func structPrefix(prefix string, field reflect.StructField) string {
+	// compileStruct reports invalid tags.
+	tag, _ := structtag.Parse(field.Tag.Get(tagName))
+	if tag.Name == "" {
+		return prefix
 	}
 
-	for _, part := range parts[1:] {
-		if strings.HasPrefix(part, "default=") {
-			fallback = strings.TrimPrefix(part, "default=")
-		}
+	return prefix + tag.Name + "."
+}
+
+// parseArgIndex parses the value of an `arg` tag.
+func parseArgIndex(arg string) (int, error) {
+	if arg == "rest" {
+		return ArgRest, nil
+	}
+
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid arg tag %q, expected a position or \"rest\"", arg)
 	}
 
-	return name, required, fallback
+	return index, nil
 }
 
 type typ struct {
@@ -181,6 +302,51 @@ type typ struct {
 	intVal    *int
 	stringVal *string
 	boolVal   *bool
+	// stringsVal is only used for positional arguments.
+	stringsVal *[]string
+}
+
+// newTyp returns the typ for a *string, *int, *bool or *[]string.
+func newTyp(to any) typ {
+	switch to := to.(type) {
+	case *string:
+		return typ{kind: reflect.String, stringVal: to}
+	case *int:
+		return typ{kind: reflect.Int, intVal: to}
+	case *bool:
+		return typ{kind: reflect.Bool, boolVal: to}
+	case *[]string:
+		return typ{kind: reflect.Slice, stringsVal: to}
+	default:
+		return typ{kind: reflect.Invalid}
+	}
+}
+
+// set parses raw according to the kind of t and stores it. For slices, raw is
+// appended.
+func (t typ) set(raw string) error {
+	switch t.kind {
+	case reflect.String:
+		*t.stringVal = raw
+	case reflect.Int:
+		val, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		*t.intVal = val
+	case reflect.Bool:
+		val, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		*t.boolVal = val
+	case reflect.Slice:
+		*t.stringsVal = append(*t.stringsVal, raw)
+	default:
+		return fmt.Errorf("unsupported type %s", t.kind)
+	}
+
+	return nil
 }
 
 func (t typ) Empty() bool {
@@ -191,6 +357,8 @@ func (t typ) Empty() bool {
 		return t.intVal == nil
 	case reflect.Bool:
 		return t.boolVal == nil
+	case reflect.Slice:
+		return t.stringsVal == nil || len(*t.stringsVal) == 0
 	default:
 		return true
 	}
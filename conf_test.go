@@ -6,8 +6,11 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // don't want to use os.Setenv and os.Getenv in tests, since it's a global
@@ -355,3 +358,272 @@ func TestLoad(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadNested(t *testing.T) {
+	type mystruct struct {
+		DB struct {
+			Host string `conf:"host"`
+		} `conf:"db"`
+		// Untagged nested structs do not add to the names.
+		Server struct {
+			Port int `conf:"port"`
+		}
+	}
+
+	env := env{"DB_HOST": "db.example.com", "PORT": "80"}
+	args := []string{"--db.host", "flag.example.com"}
+
+	var cfg mystruct
+	if err := conf.LoadEnv(&cfg, env.Get); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DB.Host != "db.example.com" || cfg.Server.Port != 80 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	if err := conf.LoadFlags(&cfg, args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DB.Host != "flag.example.com" {
+		t.Fatalf("expected value %s, got %s", "flag.example.com", cfg.DB.Host)
+	}
+}
+
+func TestLoadWithDefaults(t *testing.T) {
+	type mystruct struct {
+		Workers  int           `conf:"workers,default=1"`
+		Mode     string        `conf:"mode,default=slow"`
+		Debug    bool          `conf:"debug"`
+		Host     string        `conf:"host,required"`
+		Timeout  time.Duration // not a parameter, only set by the defaults
+		Brokers  []string
+		Fallback string `conf:"fallback,default=tag"`
+	}
+
+	defaults := mystruct{
+		Workers: 4,
+		Mode:    "fast",
+		Debug:   true,
+		Host:    "localhost",
+		Timeout: 5 * time.Second,
+		Brokers: []string{"a", "b"},
+	}
+
+	env := env{"MODE": "turbo", "DEBUG": "false"}
+
+	var cfg mystruct
+	if err := conf.Load(&cfg, conf.WithProviders(conf.NewEnvProvider(env.Get)), conf.WithDefaults(defaults)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := mystruct{
+		Workers:  4,
+		Mode:     "turbo",
+		Host:     "localhost",
+		Timeout:  5 * time.Second,
+		Brokers:  []string{"a", "b"},
+		Fallback: "tag",
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	t.Run("wrong type", func(t *testing.T) {
+		var cfg mystruct
+		err := conf.Load(&cfg, conf.WithDefaults(struct{}{}))
+		if err == nil || !strings.HasPrefix(err.Error(), "expected defaults of type") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		var cfg mystruct
+		err := conf.Load(&cfg, conf.WithDefaults((*mystruct)(nil)))
+		if err == nil || err.Error() != "expected defaults of type conf_test.mystruct, got nil *conf_test.mystruct" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("pointers to structs are copied", func(t *testing.T) {
+		type sub struct {
+			Host string `conf:"host"`
+		}
+		type withPointer struct {
+			DB *sub `conf:"db"`
+		}
+
+		defaults := withPointer{DB: &sub{Host: "localhost"}}
+		provider := conf.NewMapProvider(map[string]string{"db.host": "prod"})
+
+		var cfg withPointer
+		if err := conf.Load(&cfg, conf.WithProviders(provider), conf.WithDefaults(defaults)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.DB.Host != "prod" || defaults.DB.Host != "localhost" {
+			t.Errorf("unexpected hosts: loaded %s, defaults %s", cfg.DB.Host, defaults.DB.Host)
+		}
+	})
+}
+
+func TestLoadTagGrammar(t *testing.T) {
+	type mystruct struct {
+		Brokers string `conf:"brokers,default='a,b'"`
+		Escaped string `conf:"escaped,default=it\\'s\\, fine"`
+		Mode    string `conf:"mode,default=required"`
+		Quote   string `conf:"quote,default='\\'x\\''"`
+		Dir     string `conf:"dir,default=C:\\\\tmp"`
+	}
+
+	var cfg mystruct
+	if err := conf.LoadEnv(&cfg, env{}.Get); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := mystruct{Brokers: "a,b", Escaped: "it's, fine", Mode: "required", Quote: "'x'", Dir: `C:\tmp`}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name string
+			cfg  any
+			want string
+		}{
+			{
+				name: "misspelled option",
+				cfg: &struct {
+					Host string `conf:"host,requried"`
+				}{},
+				want: `field Host: invalid conf tag: unknown option "requried", did you mean "required"?`,
+			},
+			{
+				name: "unknown option",
+				cfg: &struct {
+					Host string `conf:"host,optional"`
+				}{},
+				want: `field Host: invalid conf tag: unknown option "optional"`,
+			},
+			{
+				name: "option with value",
+				cfg: &struct {
+					Host string `conf:"host,required=true"`
+				}{},
+				want: `field Host: invalid conf tag: option "required" does not take a value`,
+			},
+			{
+				name: "unterminated quote",
+				cfg: &struct {
+					Host string `conf:"host,default='a"`
+				}{},
+				want: `field Host: invalid conf tag: unterminated quote`,
+			},
+			{
+				name: "invalid escape",
+				cfg: &struct {
+					Dir string `conf:"dir,default=C:\\tmp\\x"`
+				}{},
+				want: `field Dir: invalid conf tag: invalid escape "\t", only \, \' and \\ can be escaped`,
+			},
+			{
+				name: "nested struct",
+				cfg: &struct {
+					DB struct {
+						Host string `conf:"host"`
+					} `conf:"db,requred"`
+				}{},
+				want: `field DB: invalid conf tag: unknown option "requred", did you mean "required"?`,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := conf.LoadEnv(tt.cfg, env{}.Get)
+				if err == nil || err.Error() != tt.want {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+		}
+	})
+}
+
+func TestLoadPointerStructs(t *testing.T) {
+	type tls struct {
+		Cert string `conf:"cert"`
+	}
+	type db struct {
+		Host string `conf:"host"`
+		TLS  *tls   `conf:"tls"`
+	}
+	type mystruct struct {
+		DB    *db `conf:"db"`
+		Cache *struct {
+			Size int `conf:"size"`
+		} `conf:"cache"`
+		// Untagged pointers are not loaded.
+		Other *tls
+	}
+
+	vars := env{"DB_HOST": "db.example.com", "DB_TLS_CERT": "cert.pem"}
+
+	t.Run("allocate", func(t *testing.T) {
+		var cfg mystruct
+		if err := conf.LoadEnv(&cfg, vars.Get); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.DB == nil || cfg.DB.Host != "db.example.com" || cfg.DB.TLS == nil || cfg.DB.TLS.Cert != "cert.pem" {
+			t.Errorf("unexpected db config: %+v", cfg.DB)
+		}
+
+		if cfg.Cache == nil || cfg.Other != nil {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("nil structs", func(t *testing.T) {
+		dbOnly := env{"DB_HOST": "db.example.com"}
+
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(conf.NewEnvProvider(dbOnly.Get)), conf.WithNilStructs()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.DB == nil || cfg.DB.Host != "db.example.com" || cfg.DB.TLS != nil || cfg.Cache != nil {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		type node struct {
+			Name string `conf:"name"`
+			Next *node  `conf:"next"`
+		}
+
+		var cfg struct {
+			Root node `conf:"root"`
+		}
+		err := conf.LoadEnv(&cfg, vars.Get)
+		if err == nil || !strings.Contains(err.Error(), "recursive type") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestLoadUnexported(t *testing.T) {
+	var cfg struct {
+		Host string `conf:"host"`
+		port int    `conf:"port"`
+		// Untagged unexported fields are ignored.
+		debug bool
+	}
+
+	err := conf.LoadEnv(&cfg, env{"PORT": "80"}.Get)
+	if err == nil || err.Error() != "field port: cannot load unexported field, export it or remove its tags" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, _ = cfg.port, cfg.debug
+}
@@ -0,0 +1,67 @@
+// Package conftest provides helpers for testing code that loads its
+// configuration with conf.
+package conftest
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+)
+
+// MapProvider is a Provider that reads the parameters from a map, keyed by
+// their names as in the `conf` tags, e.g. "db.host".
+type MapProvider = conf.MapProvider
+
+// NewMapProvider creates a new MapProvider that reads the parameters from
+// values.
+func NewMapProvider(values map[string]string) *MapProvider {
+	return conf.NewMapProvider(values)
+}
+
+// Env returns a getenv function for conf.NewEnvProvider and conf.LoadEnv that
+// reads the variables from vars instead of the environment. It fails the test
+// if a key is not an environment variable name, e.g. "db.host" instead of
+// "DB_HOST".
+func Env(t testing.TB, vars map[string]string) func(string) string {
+	t.Helper()
+
+	for key := range vars {
+		if key != strings.ToUpper(key) || strings.ContainsAny(key, ".-") {
+			t.Fatalf("conftest.Env: %q is not an environment variable name", key)
+		}
+	}
+
+	vars = maps.Clone(vars)
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+// Args returns its arguments as a slice, for conf.LoadFlags and
+// conf.NewFlagProvider.
+func Args(args ...string) []string {
+	return args
+}
+
+// AssertMissing fails the test unless err is a *conf.MissingError for exactly
+// the given parameters, in any order.
+func AssertMissing(t testing.TB, err error, names ...string) {
+	t.Helper()
+
+	var missingErr *conf.MissingError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("expected missing configuration parameters %s, got %v", strings.Join(names, ", "), err)
+	}
+
+	got := slices.Clone(missingErr.Names)
+	want := slices.Clone(names)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected missing configuration parameters %s, got %s", strings.Join(want, ", "), strings.Join(got, ", "))
+	}
+}
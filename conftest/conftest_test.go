@@ -0,0 +1,75 @@
+package conftest_test
+
+import (
+	"testing"
+
+	"github.com/solhall/conf"
+	"github.com/solhall/conf/conftest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type config struct {
+	Port  int    `conf:"port,default=8080"`
+	Debug bool   `conf:"debug"`
+	Token string `conf:"token,required"`
+	DB    struct {
+		Host string `conf:"host,required"`
+	} `conf:"db"`
+}
+
+func TestMapProvider(t *testing.T) {
+	provider := conftest.NewMapProvider(map[string]string{
+		"db.host": "db.example.com",
+		"token":   "secret",
+		"debug":   "true",
+	})
+
+	var cfg config
+	if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := config{Port: 8080, Debug: true, Token: "secret"}
+	want.DB.Host = "db.example.com"
+
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	t.Run("invalid value", func(t *testing.T) {
+		var cfg config
+		provider := conftest.NewMapProvider(map[string]string{"port": "abc"})
+		if err := conf.Load(&cfg, conf.WithProviders(provider)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestAssertMissing(t *testing.T) {
+	var cfg config
+	err := conf.Load(&cfg, conf.WithProviders(conftest.NewMapProvider(nil)))
+	conftest.AssertMissing(t, err, "token", "db.host")
+}
+
+func TestEnv(t *testing.T) {
+	var cfg config
+	err := conf.LoadEnv(&cfg, conftest.Env(t, map[string]string{"DB_HOST": "db.example.com"}))
+	conftest.AssertMissing(t, err, "token")
+
+	if cfg.DB.Host != "db.example.com" {
+		t.Errorf("expected value %s, got %s", "db.example.com", cfg.DB.Host)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	var cfg config
+	err := conf.LoadFlags(&cfg, conftest.Args("--token", "secret", "--db.host=db.example.com", "-port", "80"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Port != 80 || cfg.Token != "secret" || cfg.DB.Host != "db.example.com" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
@@ -0,0 +1,83 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	_ Provider   = (*DirProvider)(nil)
+	_ FileSource = (*DirProvider)(nil)
+)
+
+// kubernetesDataDir is the symlink Kubernetes swaps to a new directory to
+// update the files of a ConfigMap or Secret volume atomically.
+const kubernetesDataDir = "..data"
+
+// DirProvider reads configuration from a directory with one file per
+// parameter, where the file name is the name of the parameter and its content
+// the value. This is how Kubernetes projects ConfigMaps and Secrets into
+// volumes. Trailing newlines are trimmed from the values.
+//
+// The parameters of nested structs are read from subdirectories, e.g. "db.host"
+// from the file db/host.
+type DirProvider struct {
+	path string
+	lookupVars
+}
+
+// NewDirProvider creates a new DirProvider that reads the files in the
+// directory path.
+func NewDirProvider(path string) *DirProvider {
+	return &DirProvider{
+		path:       path,
+		lookupVars: newLookupVars(),
+	}
+}
+
+// Load reads the configuration from the files in the directory. Missing files
+// are treated like unset parameters.
+func (p *DirProvider) Load() error {
+	// Kubernetes updates a volume by writing the files to a new directory
+	// and pointing ..data to it. Resolve it once, so that all values are
+	// read from the same version of the volume.
+	root := p.path
+	if dir, err := filepath.EvalSymlinks(filepath.Join(p.path, kubernetesDataDir)); err == nil {
+		root = dir
+	}
+
+	return p.load(func(name string) (string, error) {
+		content, err := os.ReadFile(filepath.Join(root, dirName(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	})
+}
+
+// Files returns the files of the registered parameters, so that Watch can
+// reload the configuration when they change.
+func (p *DirProvider) Files() []string {
+	files := make([]string, 0, len(p.m))
+	for name := range p.m {
+		files = append(files, filepath.Join(p.path, dirName(name)))
+	}
+	slices.Sort(files)
+
+	return files
+}
+
+// dirName returns the path of the file of a parameter relative to the
+// directory, e.g. "db/host" for "db.host".
+func dirName(name string) string {
+	return filepath.FromSlash(strings.ReplaceAll(name, ".", "/"))
+}
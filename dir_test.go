@@ -0,0 +1,100 @@
+package conf_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeVolume writes files into a new version directory of a Kubernetes
+// volume at dir and atomically points the ..data symlink to it.
+func writeVolume(t *testing.T, dir, version string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, version, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(version, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"port", "db"} {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join("..data", name), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDirProvider(t *testing.T) {
+	type mystruct struct {
+		Port int `conf:"port"`
+		DB   struct {
+			Host     string `conf:"host,required"`
+			Password string `conf:"password,default=none"`
+		} `conf:"db"`
+	}
+
+	dir := t.TempDir()
+	writeVolume(t, dir, "..v1", map[string]string{
+		"port":    "80\n",
+		"db/host": "db.example.com\n",
+	})
+
+	provider := conf.NewDirProvider(dir)
+
+	var cfg mystruct
+	if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want mystruct
+	want.Port = 80
+	want.DB.Host = "db.example.com"
+	want.DB.Password = "none"
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	t.Run("data swap", func(t *testing.T) {
+		writeVolume(t, dir, "..v2", map[string]string{
+			"port":    "81",
+			"db/host": "db2.example.com",
+		})
+
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.Port != 81 || cfg.DB.Host != "db2.example.com" {
+			t.Errorf("unexpected config after swap: %+v", cfg)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var cfg mystruct
+		err := conf.Load(&cfg, conf.WithProviders(conf.NewDirProvider(t.TempDir())))
+		if err == nil || err.Error() != "missing configuration parameters: db.host" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
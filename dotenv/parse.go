@@ -8,8 +8,11 @@ import (
 	"strings"
 )
 
+// DefaultFile is the file read by Parse.
+const DefaultFile = ".env"
+
 func Parse() (map[string]string, error) {
-	fname := ".env"
+	fname := DefaultFile
 	f, err := os.Open(fname)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
@@ -0,0 +1,162 @@
+//go:build !tinygo
+
+package conf
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	encPrefix = "ENC[secretbox,"
+	encSuffix = "]"
+	nonceSize = 24
+)
+
+// GenerateKey returns a new random key for EncryptValue.
+func GenerateKey() (*[32]byte, error) {
+	key := new([32]byte)
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ParseKey parses a base64 encoded key, as written to key files.
+func ParseKey(s string) (*[32]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+
+	if len(b) != 32 {
+		return nil, fmt.Errorf("expected a 32 byte key, got %d bytes", len(b))
+	}
+
+	key := new([32]byte)
+	copy(key[:], b)
+
+	return key, nil
+}
+
+// FormatKey returns the base64 encoding of key, as read by ParseKey.
+func FormatKey(key *[32]byte) string {
+	return base64.StdEncoding.EncodeToString(key[:])
+}
+
+// IsEncrypted reports whether value has the form ENC[secretbox,...].
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+// EncryptValue encrypts plaintext with NaCl secretbox and returns it in the
+// form ENC[secretbox,<base64 of nonce and ciphertext>].
+func EncryptValue(plaintext string, key *[32]byte) (string, error) {
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := secretbox.Seal(nonce[:], []byte(plaintext), &nonce, key)
+
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed) + encSuffix, nil
+}
+
+// DecryptValue decrypts a value encrypted with EncryptValue.
+func DecryptValue(value string, key *[32]byte) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encPrefix), encSuffix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+
+	if len(sealed) < nonceSize+secretbox.Overhead {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[:nonceSize])
+
+	plaintext, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, key)
+	if !ok {
+		return "", errors.New("failed to decrypt value, wrong key?")
+	}
+
+	return string(plaintext), nil
+}
+
+// WithDecryptionKey returns a LoadOption that decrypts every string parameter
+// whose value has the form ENC[secretbox,...], whichever provider it came
+// from, e.g. a .env file committed to git.
+func WithDecryptionKey(key *[32]byte) LoadOption {
+	return withDecryption(func() (*[32]byte, error) {
+		return key, nil
+	})
+}
+
+// WithKeyFile is like WithDecryptionKey, but reads the base64 encoded key from
+// a file when loading.
+func WithKeyFile(path string) LoadOption {
+	return withDecryption(func() (*[32]byte, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+
+		return ParseKey(string(content))
+	})
+}
+
+// withDecryption returns a LoadOption that decrypts the loaded values with the
+// key returned by getKey, which is only called if a value is encrypted.
+func withDecryption(getKey func() (*[32]byte, error)) LoadOption {
+	return func(c *loadConfig) {
+		c.decrypt = func(v reflect.Value, fields []schemaField) error {
+			return decrypt(v, fields, getKey)
+		}
+	}
+}
+
+// decrypt decrypts the encrypted string parameters of the struct v, whose
+// fields are described by fields.
+func decrypt(v reflect.Value, fields []schemaField, getKey func() (*[32]byte, error)) error {
+	var key *[32]byte
+	var errs []error
+	for _, f := range fields {
+		if f.arg || f.kind != reflect.String {
+			continue
+		}
+
+		field := v.FieldByIndex(f.index)
+		if !IsEncrypted(field.String()) {
+			continue
+		}
+
+		if key == nil {
+			var err error
+			if key, err = getKey(); err != nil {
+				return err
+			}
+		}
+
+		plaintext, err := DecryptValue(field.String(), key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to decrypt %s: %w", f.name, err))
+			continue
+		}
+		field.SetString(plaintext)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+//go:build !tinygo
+
+package conf_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+)
+
+func TestEncryptedValues(t *testing.T) {
+	type mystruct struct {
+		DBPassword string `conf:"db_password,required"`
+		Port       int    `conf:"port"`
+	}
+
+	key, err := conf.GenerateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	encrypted, err := conf.EncryptValue("hunter2", key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !conf.IsEncrypted(encrypted) || strings.Contains(encrypted, "hunter2") {
+		t.Fatalf("unexpected encrypted value: %s", encrypted)
+	}
+
+	dotenv := func() io.ReadCloser {
+		return io.NopCloser(strings.NewReader("DB_PASSWORD=" + encrypted + "\nPORT=80"))
+	}
+
+	t.Run("key", func(t *testing.T) {
+		var cfg mystruct
+		if err := conf.Load(&cfg,
+			conf.WithProviders(conf.NewEnvProvider(env{}.Get).WithDotEnv(dotenv())),
+			conf.WithDecryptionKey(key),
+		); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.DBPassword != "hunter2" || cfg.Port != 80 {
+			t.Fatalf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("key file", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "key")
+		if err := os.WriteFile(keyFile, []byte(conf.FormatKey(key)+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var cfg mystruct
+		if err := conf.Load(&cfg,
+			conf.WithProviders(conf.NewEnvProvider(env{}.Get).WithDotEnv(dotenv())),
+			conf.WithKeyFile(keyFile),
+		); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.DBPassword != "hunter2" {
+			t.Fatalf("expected value %s, got %s", "hunter2", cfg.DBPassword)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherKey, err := conf.GenerateKey()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var cfg mystruct
+		err = conf.Load(&cfg,
+			conf.WithProviders(conf.NewEnvProvider(env{}.Get).WithDotEnv(dotenv())),
+			conf.WithDecryptionKey(otherKey),
+		)
+		if err == nil || err.Error() != "failed to decrypt db_password: failed to decrypt value, wrong key?" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -1,22 +1,22 @@
 package conf
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"reflect"
+	"os"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/solhall/conf/dotenv"
+	"github.com/solhall/conf/internal/suggest"
 )
 
 func NewEnvProvider(getenv func(string) string) *EnvProvider {
 	return &EnvProvider{
-		getenv:    getenv,
-		m:         make(map[string]typ),
-		fallbacks: make(map[string]string),
-		missing:   []string{},
+		lookupVars: newLookupVars(),
+		getenv:     getenv,
+		fileVars:   make(map[string]bool),
 	}
 }
 
@@ -29,136 +29,216 @@ func (p *EnvProvider) WithDotEnv(r io.ReadCloser) *EnvProvider {
 	return p
 }
 
+// WithPrefix sets a prefix that is prepended to the environment variable of
+// every parameter, e.g. with the prefix "MYAPP_", the parameter "port" is read
+// from MYAPP_PORT. Pass the same prefix to GenerateDotEnv and GenerateMarkdown
+// with WithEnvPrefix.
+func (p *EnvProvider) WithPrefix(prefix string) *EnvProvider {
+	p.prefix = prefix
+	return p
+}
+
+// WithUnknownVars makes Load look for environment variables that start with
+// the prefix but do not belong to any registered parameter, which usually are
+// typos. environ lists the environment like os.Environ. Variables from the
+// .env file are checked as well.
+//
+// If warn is nil, Load fails with the unknown variables, otherwise warn is
+// called for each of them and loading continues. Nothing is checked without a
+// prefix, see WithPrefix.
+func (p *EnvProvider) WithUnknownVars(environ func() []string, warn func(err *UnknownVarError)) *EnvProvider {
+	p.environ = environ
+	p.warnUnknown = warn
+	return p
+}
+
+// WithFileSuffix makes Load read every parameter whose environment variable is
+// unset from the file named by the variable with the suffix _FILE, e.g.
+// DB_PASSWORD from the file in DB_PASSWORD_FILE, as used for Docker and
+// Kubernetes secrets. Trailing newlines are trimmed from the file content.
+//
+// To do this only for some parameters, tag them with the "file" option
+// instead, e.g. `conf:"db_password,file"`.
+func (p *EnvProvider) WithFileSuffix() *EnvProvider {
+	p.fileSuffix = true
+	return p
+}
+
 type EnvProvider struct {
 	withDotEnv   bool
 	dotEnvReader io.ReadCloser
-
-	getenv    func(string) string
-	m         map[string]typ
-	fallbacks map[string]string
-	required  []string
-	// missing is a list of missing required configuration parameters. If a
-	// parameter does not have a value after considering the fallbacks map
-	// and it is required, it will be considered missing.
-	missing []string
+	// dotenvs are the variables from the .env file. When they are read
+	// from dotEnvReader, they are kept for later loads, since the reader
+	// can only be read once.
+	dotenvs          map[string]string
+	dotEnvFromReader bool
+
+	prefix      string
+	environ     func() []string
+	warnUnknown func(err *UnknownVarError)
+
+	// fileSuffix enables the _FILE suffix for all parameters, fileVars
+	// for the ones tagged with the "file" option.
+	fileSuffix bool
+	fileVars   map[string]bool
+
+	getenv func(string) string
+	lookupVars
 }
 
-var _ Provider = (*EnvProvider)(nil)
+var (
+	_ TagOptionProvider = (*EnvProvider)(nil)
+	_ FileSource        = (*EnvProvider)(nil)
+)
 
 // Load reads the configuration from the environment variables.
 func (p *EnvProvider) Load() error {
-	if p.withDotEnv {
+	if p.withDotEnv && !p.dotEnvFromReader {
 		if err := p.AddDotEnv(); err != nil {
 			return fmt.Errorf("failed to add .env file: %w", err)
 		}
 	}
 
+	if p.environ != nil && p.prefix != "" {
+		if err := p.checkUnknown(); err != nil {
+			return err
+		}
+	}
+
 	getenv := func(name string) string {
-		uppersnake := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
-		return p.getenv(uppersnake)
+		key := p.prefix + envName(name)
+		if v, ok := p.dotenvs[key]; ok {
+			return v
+		}
+
+		return p.getenv(key)
 	}
 
-	for name, to := range p.m {
+	return p.load(func(name string) (string, error) {
 		rawVal := getenv(name)
-		if rawVal == "" {
-			if fallback, ok := p.fallbacks[name]; ok {
-				rawVal = fallback
+		if rawVal == "" && (p.fileSuffix || p.fileVars[name]) {
+			if path := getenv(name + fileEnvSuffix); path != "" {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return "", fmt.Errorf("failed to read %s from file: %w", name, err)
+				}
+				rawVal = strings.TrimRight(string(content), "\r\n")
 			}
 		}
 
-		if rawVal == "" {
-			if slices.Contains(p.required, name) {
-				p.missing = append(p.missing, name)
-			}
+		return rawVal, nil
+	})
+}
 
-			continue
-		}
+// fileEnvSuffix is appended to the environment variable of a parameter to get
+// the variable holding the path of a file with its value.
+const fileEnvSuffix = "_FILE"
 
-		switch to.kind {
-		case reflect.String:
-			*to.stringVal = rawVal
-		case reflect.Int:
-			val, err := strconv.Atoi(rawVal)
-			if err != nil {
-				return fmt.Errorf("failed to parse %s as int: %w", name, err)
-			}
-			*to.intVal = val
-		case reflect.Bool:
-			val, err := strconv.ParseBool(rawVal)
-			if err != nil {
-				return fmt.Errorf("failed to parse %s as bool: %w", name, err)
-			}
-			*to.boolVal = val
-		default:
-			return fmt.Errorf("field %s has unsupported type %s", name, to.kind)
-		}
+// TagOption enables the "file" option for the parameter with the given name.
+func (p *EnvProvider) TagOption(name, option string) {
+	if option == "file" {
+		p.fileVars[name] = true
 	}
+}
 
-	return nil
+// envName returns the environment variable name for a configuration
+// parameter, e.g. "field-name" becomes "FIELD_NAME" and "db.host" becomes
+// "DB_HOST".
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
 }
 
+// AddDotEnv reads the .env file, or the reader given to WithDotEnv. Its
+// variables take precedence over the environment.
 func (p *EnvProvider) AddDotEnv() error {
-	var dotenvs map[string]string
-	var err error
 	if p.dotEnvReader != nil {
-		dotenvs, err = dotenv.ParseReader(p.dotEnvReader)
+		dotenvs, err := dotenv.ParseReader(p.dotEnvReader)
 		if err != nil {
 			return fmt.Errorf("failed to parse .env file: %w", err)
 		}
 		if err := p.dotEnvReader.Close(); err != nil {
 			return fmt.Errorf("failed to close .env file: %w", err)
 		}
-	} else {
-		dotenvs, err = dotenv.Parse()
-		if err != nil {
-			return fmt.Errorf("failed to parse .env file: %w", err)
-		}
-	}
 
-	defaultGetenv := p.getenv
-	p.getenv = func(name string) string {
-		if v, ok := dotenvs[name]; ok {
-			return v
-		}
+		p.dotenvs = dotenvs
+		p.dotEnvReader = nil
+		p.dotEnvFromReader = true
+		return nil
+	}
 
-		return defaultGetenv(name)
+	dotenvs, err := dotenv.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse .env file: %w", err)
 	}
+	p.dotenvs = dotenvs
 
 	return nil
 }
 
-func (p *EnvProvider) StringVar(to *string, name, fallback string, required bool) {
-	p.m[name] = typ{kind: reflect.String, stringVal: to}
-	if fallback != "" {
-		p.fallbacks[name] = fallback
-	}
-	if required {
-		p.required = append(p.required, name)
+// Files returns the .env file if it is read from disk, so that Watch can
+// reload the configuration when it changes.
+func (p *EnvProvider) Files() []string {
+	if !p.withDotEnv || p.dotEnvReader != nil || p.dotEnvFromReader {
+		return nil
 	}
+
+	return []string{dotenv.DefaultFile}
 }
 
-func (p *EnvProvider) IntVar(to *int, name string, fallback int, required bool) {
-	p.m[name] = typ{kind: reflect.Int, intVal: to}
-	if fallback != 0 {
-		p.fallbacks[name] = fmt.Sprintf("%d", fallback)
-	}
-	if required {
-		p.required = append(p.required, name)
+// UnknownVarError is reported for an environment variable that starts with
+// the prefix of an EnvProvider but does not belong to any parameter.
+type UnknownVarError struct {
+	// Name is the name of the unknown environment variable.
+	Name string
+	// Suggestion is the most similar known environment variable, or empty
+	// if none is similar enough.
+	Suggestion string
+}
+
+func (e *UnknownVarError) Error() string {
+	if e.Suggestion == "" {
+		return "unknown environment variable " + e.Name
 	}
+
+	return fmt.Sprintf("unknown environment variable %s, did you mean %s?", e.Name, e.Suggestion)
 }
 
-func (p *EnvProvider) BoolVar(to *bool, name string, fallback bool, required bool) {
-	p.m[name] = typ{kind: reflect.Bool, boolVal: to}
-	if fallback {
-		p.fallbacks[name] = "true"
-	} else {
-		p.fallbacks[name] = "false"
+// checkUnknown reports the environment variables with the prefix that do not
+// belong to any registered parameter.
+func (p *EnvProvider) checkUnknown() error {
+	known := make([]string, 0, len(p.m))
+	for name := range p.m {
+		known = append(known, p.prefix+envName(name))
+		if p.fileSuffix || p.fileVars[name] {
+			known = append(known, p.prefix+envName(name+fileEnvSuffix))
+		}
+	}
+	slices.Sort(known)
+
+	var names []string
+	for _, kv := range p.environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
 	}
-	if required {
-		p.required = append(p.required, name)
+	for name := range p.dotenvs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	var errs []error
+	for _, name := range names {
+		if !strings.HasPrefix(name, p.prefix) || slices.Contains(known, name) {
+			continue
+		}
+
+		err := &UnknownVarError{Name: name, Suggestion: suggest.Closest(name, known)}
+		if p.warnUnknown != nil {
+			p.warnUnknown(err)
+			continue
+		}
+		errs = append(errs, err)
 	}
-}
 
-func (p *EnvProvider) Missing() []string {
-	return p.missing
+	return errors.Join(errs...)
 }
@@ -0,0 +1,164 @@
+package conf_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func (e env) Environ() []string {
+	var environ []string
+	for k, v := range e {
+		environ = append(environ, k+"="+v)
+	}
+
+	return environ
+}
+
+func TestEnvProviderPrefix(t *testing.T) {
+	type mystruct struct {
+		DatabaseURL string `conf:"database_url"`
+	}
+
+	env := env{"MYAPP_DATABASE_URL": "postgres://", "DATABASE_URL": "ignored"}
+
+	var cfg mystruct
+	if err := conf.Load(&cfg, conf.WithProviders(
+		conf.NewEnvProvider(env.Get).WithPrefix("MYAPP_"),
+	)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DatabaseURL != "postgres://" {
+		t.Fatalf("expected value %s, got %s", "postgres://", cfg.DatabaseURL)
+	}
+}
+
+func TestEnvProviderUnknownVars(t *testing.T) {
+	type mystruct struct {
+		DatabaseURL string `conf:"database_url"`
+		Port        int    `conf:"port"`
+	}
+
+	env := env{
+		"MYAPP_DATABSE_URL": "postgres://",
+		"MYAPP_PORT":        "80",
+		"MYAPP_SOMETHING":   "else",
+		"HOME":              "/root",
+	}
+
+	t.Run("error", func(t *testing.T) {
+		var cfg mystruct
+		err := conf.Load(&cfg, conf.WithProviders(
+			conf.NewEnvProvider(env.Get).WithPrefix("MYAPP_").WithUnknownVars(env.Environ, nil),
+		))
+
+		want := "failed to load configuration: failed to load configuration with *conf.EnvProvider: " +
+			"unknown environment variable MYAPP_DATABSE_URL, did you mean MYAPP_DATABASE_URL?\n" +
+			"unknown environment variable MYAPP_SOMETHING"
+		if err == nil || err.Error() != want {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		var warnings []string
+		warn := func(err *conf.UnknownVarError) {
+			warnings = append(warnings, err.Name+":"+err.Suggestion)
+		}
+
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(
+			conf.NewEnvProvider(env.Get).WithPrefix("MYAPP_").WithUnknownVars(env.Environ, warn),
+		)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"MYAPP_DATABSE_URL:MYAPP_DATABASE_URL", "MYAPP_SOMETHING:"}
+		if diff := cmp.Diff(want, warnings); diff != "" {
+			t.Errorf("unexpected warnings (-want +got):\n%s", diff)
+		}
+
+		if cfg.Port != 80 {
+			t.Errorf("expected value %d, got %d", 80, cfg.Port)
+		}
+	})
+}
+
+func TestEnvProviderFileSuffix(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	t.Run("tag option", func(t *testing.T) {
+		type mystruct struct {
+			DBPassword string `conf:"db_password,required,file"`
+			APIKey     string `conf:"api_key,default=none"`
+		}
+
+		env := env{"DB_PASSWORD_FILE": secret, "API_KEY_FILE": secret}
+
+		var cfg mystruct
+		if err := conf.LoadEnv(&cfg, env.Get); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := mystruct{DBPassword: "s3cret", APIKey: "none"}
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("global", func(t *testing.T) {
+		type mystruct struct {
+			DBPassword string `conf:"db_password,required"`
+			APIKey     string `conf:"api_key"`
+		}
+
+		env := env{"DB_PASSWORD_FILE": secret, "API_KEY": "from-env", "API_KEY_FILE": secret}
+
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(
+			conf.NewEnvProvider(env.Get).WithFileSuffix(),
+		)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := mystruct{DBPassword: "s3cret", APIKey: "from-env"}
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		type mystruct struct {
+			DBPassword string `conf:"db_password,required,file"`
+		}
+
+		var cfg mystruct
+		err := conf.LoadEnv(&cfg, env{}.Get)
+		if err == nil || err.Error() != "missing configuration parameters: db_password" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		type mystruct struct {
+			DBPassword string `conf:"db_password,file"`
+		}
+
+		var cfg mystruct
+		err := conf.LoadEnv(&cfg, env{"DB_PASSWORD_FILE": filepath.Join(dir, "nope")}.Get)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
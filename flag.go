@@ -1,19 +1,54 @@
 package conf
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 )
 
-var _ Provider = (*FlagProvider)(nil)
+// setFlagName is the name of the flag that overrides parameters by name.
+const setFlagName = "set"
 
+var (
+	_ ShortFlagProvider = (*FlagProvider)(nil)
+	_ TagOptionProvider = (*FlagProvider)(nil)
+	_ ArgProvider       = (*FlagProvider)(nil)
+)
+
+// FlagProvider reads configuration from command line arguments, following
+// the GNU conventions:
+//
+//	--port=80 --port 80   long flags, with or without "="
+//	-port 80              long flags with a single dash, like the flag package
+//	-p 80 -p80            short flags, set with the `short` struct tag
+//	-vx                   bundled short flags
+//	-vvv                  counting, for int flags with the "count" option
+//	--no-feature          sets a bool flag to false
+//	--                    stops flag parsing
+//	--set db.host=x       sets any parameter by its name, see below
+//
+// The --set flag overrides parameters by their name, including nested ones
+// and those with a short name only, like `helm --set`. Lists, i.e. string
+// parameters with the "list" option like `conf:"brokers,list"`, are set as a
+// whole with --set brokers=a,b, or an element at a time with
+// --set brokers[1]=b. A parameter named "set" takes precedence over the flag.
+//
+// Parsing stops at the first argument that is not a flag, or after "--". The
+// remaining arguments are bound to the fields tagged with `arg`, and passed to
+// the remaining function, if any.
 type FlagProvider struct {
-	m        map[string]typ
-	fs       *flag.FlagSet
+	m      map[string]typ
+	shorts map[string]string
+	// counters are the int flags with the "count" option.
+	counters map[string]bool
+	// lists are the string flags with the "list" option, whose elements
+	// can be set with --set.
+	lists    map[string]bool
+	posArgs  []posArg
 	required []string
 	missing  []string
 	args     []string
@@ -23,20 +58,19 @@ type FlagProvider struct {
 
 // NewFlagProvider creates a new FlagProvider that reads flags into `conf`
 // tags.
-// The remainingFunc is called with the remaining arguments, useful for
-// subcommands. Can be nil.
 func NewFlagProvider(args []string) *FlagProvider {
-	fs := flag.NewFlagSet("confflags", flag.ContinueOnError)
-	fs.SetOutput(io.Discard)
-
 	return &FlagProvider{
-		m:       make(map[string]typ),
-		fs:      fs,
-		missing: []string{},
-		args:    args,
+		m:        make(map[string]typ),
+		shorts:   make(map[string]string),
+		counters: make(map[string]bool),
+		lists:    make(map[string]bool),
+		missing:  []string{},
+		args:     args,
 	}
 }
 
+// WithRemainingFunc sets a function that is called with the arguments left
+// after parsing the flags, useful for subcommands.
 func (p *FlagProvider) WithRemainingFunc(f func(remaining []string)) *FlagProvider {
 	p.remainingFunc = f
 	return p
@@ -46,8 +80,8 @@ func (p *FlagProvider) StringVar(to *string, name, fallback string, required boo
 	name = p.normalizeName(name)
 
 	p.m[name] = typ{kind: reflect.String, stringVal: to}
-	p.fs.StringVar(to, name, fallback, "")
-	if required {
+	*to = fallback
+	if required && !slices.Contains(p.required, name) {
 		p.required = append(p.required, name)
 	}
 }
@@ -56,8 +90,8 @@ func (p *FlagProvider) IntVar(to *int, name string, fallback int, required bool)
 	name = p.normalizeName(name)
 
 	p.m[name] = typ{kind: reflect.Int, intVal: to}
-	p.fs.IntVar(to, name, fallback, "")
-	if required {
+	*to = fallback
+	if required && !slices.Contains(p.required, name) {
 		p.required = append(p.required, name)
 	}
 }
@@ -66,14 +100,63 @@ func (p *FlagProvider) BoolVar(to *bool, name string, fallback bool, required bo
 	name = p.normalizeName(name)
 
 	p.m[name] = typ{kind: reflect.Bool, boolVal: to}
-	p.fs.BoolVar(to, name, fallback, "")
-	if required {
+	*to = fallback
+	if required && !slices.Contains(p.required, name) {
 		p.required = append(p.required, name)
 	}
 }
 
+// posArg is a positional argument registered with ArgVar.
+type posArg struct {
+	to       typ
+	name     string
+	index    int
+	fallback string
+	required bool
+}
+
+// ArgVar registers a pointer that will be set to the positional argument at
+// index, or to the arguments after the numbered ones if index is ArgRest.
+func (p *FlagProvider) ArgVar(to any, name string, index int, fallback string, required bool) {
+	arg := posArg{
+		to:       newTyp(to),
+		name:     name,
+		index:    index,
+		fallback: fallback,
+		required: required,
+	}
+
+	i := slices.IndexFunc(p.posArgs, func(a posArg) bool { return a.name == name })
+	if i >= 0 {
+		p.posArgs[i] = arg
+		return
+	}
+	p.posArgs = append(p.posArgs, arg)
+}
+
+// ShortVar registers a single letter alias for the flag with the given name.
+func (p *FlagProvider) ShortVar(name, short string) {
+	p.shorts[short] = p.normalizeName(name)
+}
+
+// TagOption enables the "count" option for the int flag with the given name,
+// which makes repeating its short name count up, e.g. -vvv sets it to 3, or
+// the "list" option for the string flag, which makes its comma separated
+// elements settable with --set name[index]=value.
+func (p *FlagProvider) TagOption(name, option string) {
+	switch option {
+	case "count":
+		p.counters[p.normalizeName(name)] = true
+	case "list":
+		p.lists[p.normalizeName(name)] = true
+	}
+}
+
 func (p *FlagProvider) Load() error {
-	if err := p.fs.Parse(p.args); err != nil {
+	p.missing = []string{}
+
+	remaining, err := p.parse(p.args)
+	if err != nil {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 
@@ -83,9 +166,13 @@ func (p *FlagProvider) Load() error {
 		}
 	}
 
+	if err := p.bindArgs(remaining); err != nil {
+		return err
+	}
+
 	// Pass remaining arguments to the remainingFunc
 	if p.remainingFunc != nil {
-		p.remainingFunc(p.fs.Args())
+		p.remainingFunc(remaining)
 	}
 
 	return nil
@@ -95,6 +182,318 @@ func (p *FlagProvider) Missing() []string {
 	return p.missing
 }
 
+// bindArgs sets the positional arguments from the arguments left after the
+// flags.
+func (p *FlagProvider) bindArgs(args []string) error {
+	rest := 0
+	for _, arg := range p.posArgs {
+		if arg.index >= rest {
+			rest = arg.index + 1
+		}
+	}
+
+	for _, arg := range p.posArgs {
+		var values []string
+		switch {
+		case arg.index == ArgRest && rest < len(args):
+			values = args[rest:]
+		case arg.index != ArgRest && arg.index < len(args):
+			values = args[arg.index : arg.index+1]
+		case arg.fallback != "":
+			values = []string{arg.fallback}
+		}
+
+		if arg.to.kind == reflect.Slice {
+			*arg.to.stringsVal = nil
+		}
+
+		for _, value := range values {
+			if err := arg.to.set(value); err != nil {
+				return fmt.Errorf("invalid value %q for argument %s: %w", value, arg.name, err)
+			}
+		}
+
+		if len(values) == 0 && arg.required {
+			p.missing = append(p.missing, arg.name)
+		}
+	}
+
+	return nil
+}
+
+// parse sets the flags found in args and returns the remaining arguments.
+func (p *FlagProvider) parse(args []string) ([]string, error) {
+	// counted tracks the int flags incremented by repeating their short
+	// name, so that counting starts at zero rather than at the fallback.
+	counted := make(map[string]bool)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[i+1:], nil
+		}
+
+		if len(arg) < 2 || arg[0] != '-' {
+			return args[i:], nil
+		}
+
+		var (
+			consumed int
+			err      error
+		)
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			consumed, err = p.parseLong("--", arg[2:], args[i+1:])
+		case p.isLong(arg[1:]):
+			consumed, err = p.parseLong("-", arg[1:], args[i+1:])
+		default:
+			consumed, err = p.parseShort(arg[1:], args[i+1:], counted)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		i += consumed
+	}
+
+	return nil, nil
+}
+
+// isLong reports whether a single dash argument names a long flag, which is
+// supported for compatibility with the flag package.
+func (p *FlagProvider) isLong(arg string) bool {
+	name, _, _ := strings.Cut(arg, "=")
+	if _, ok := p.m[name]; ok {
+		return true
+	}
+
+	if name == setFlagName {
+		return true
+	}
+
+	if name, ok := strings.CutPrefix(name, "no-"); ok {
+		if to, ok := p.m[name]; ok && to.kind == reflect.Bool {
+			return true
+		}
+	}
+
+	// Let unknown multi-letter flags fail with their full name.
+	return len(name) > 1 && p.shorts[name[:1]] == ""
+}
+
+// parseLong parses a long flag, without its dashes, and returns the number of
+// arguments from rest it consumed as its value.
+func (p *FlagProvider) parseLong(dashes, arg string, rest []string) (int, error) {
+	name, value, hasValue := strings.Cut(arg, "=")
+	to, ok := p.m[name]
+	if !ok && name == setFlagName {
+		consumed := 0
+		if !hasValue {
+			if len(rest) == 0 {
+				return 0, fmt.Errorf("flag needs an argument: %s%s", dashes, name)
+			}
+			value = rest[0]
+			consumed = 1
+		}
+
+		if err := p.set(value); err != nil {
+			return 0, fmt.Errorf("invalid value %q for flag %s%s: %w", value, dashes, name, err)
+		}
+
+		return consumed, nil
+	}
+	if !ok {
+		if negated, ok := strings.CutPrefix(name, "no-"); ok && !hasValue {
+			if to, ok := p.m[negated]; ok && to.kind == reflect.Bool {
+				*to.boolVal = false
+				return 0, nil
+			}
+		}
+
+		return 0, p.undefined(dashes + name)
+	}
+
+	if to.kind == reflect.Bool && !hasValue {
+		*to.boolVal = true
+		return 0, nil
+	}
+
+	consumed := 0
+	if !hasValue {
+		if !takesValue(to, rest) {
+			return 0, fmt.Errorf("flag needs an argument: %s%s", dashes, name)
+		}
+		value = rest[0]
+		consumed = 1
+	}
+
+	if err := to.set(value); err != nil {
+		return 0, fmt.Errorf("invalid value %q for flag %s%s: %w", value, dashes, name, err)
+	}
+
+	return consumed, nil
+}
+
+// parseShort parses a bundle of short flags, without its dash, and returns the
+// number of arguments from rest it consumed as a value.
+func (p *FlagProvider) parseShort(bundle string, rest []string, counted map[string]bool) (int, error) {
+	for j := 0; j < len(bundle); j++ {
+		short := bundle[j : j+1]
+		name, ok := p.shorts[short]
+		if !ok {
+			return 0, p.undefined("-" + short)
+		}
+
+		to := p.m[name]
+		value := strings.TrimPrefix(bundle[j+1:], "=")
+
+		switch to.kind {
+		case reflect.Bool:
+			if value != bundle[j+1:] {
+				// -v=false
+				if err := to.set(value); err != nil {
+					return 0, fmt.Errorf("invalid value %q for flag -%s: %w", value, short, err)
+				}
+				return 0, nil
+			}
+			*to.boolVal = true
+			continue
+		case reflect.Int:
+			if !p.counters[name] {
+				break
+			}
+
+			// A counter takes the rest of the bundle as its value if
+			// it is a number, and otherwise counts how often it is
+			// repeated, e.g. -vvv.
+			if value != bundle[j+1:] || (value != "" && isInt(value)) {
+				if err := to.set(value); err != nil {
+					return 0, fmt.Errorf("invalid value %q for flag -%s: %w", value, short, err)
+				}
+				return 0, nil
+			}
+
+			if !counted[name] {
+				counted[name] = true
+				*to.intVal = 0
+			}
+			*to.intVal++
+			continue
+		}
+
+		consumed := 0
+		if value == "" {
+			if !takesValue(to, rest) {
+				return 0, fmt.Errorf("flag needs an argument: -%s", short)
+			}
+			value = rest[0]
+			consumed = 1
+		}
+
+		if err := to.set(value); err != nil {
+			return 0, fmt.Errorf("invalid value %q for flag -%s: %w", value, short, err)
+		}
+
+		return consumed, nil
+	}
+
+	return 0, nil
+}
+
+// set applies an override of the form name=value or name[index]=value.
+func (p *FlagProvider) set(override string) error {
+	key, value, ok := strings.Cut(override, "=")
+	if !ok {
+		return errors.New("expected name=value")
+	}
+
+	name, index, err := parseSetKey(key)
+	if err != nil {
+		return err
+	}
+
+	to, ok := p.m[p.normalizeName(name)]
+	if !ok {
+		return fmt.Errorf("unknown parameter %s", name)
+	}
+
+	if index < 0 {
+		return to.set(value)
+	}
+
+	// Only lists are split, since other strings may contain commas, e.g.
+	// a DSN.
+	if !p.lists[p.normalizeName(name)] {
+		return fmt.Errorf("parameter %s is not a list", name)
+	}
+
+	var items []string
+	if *to.stringVal != "" {
+		items = strings.Split(*to.stringVal, ",")
+	}
+
+	switch {
+	case index < len(items):
+		items[index] = value
+	case index == len(items):
+		items = append(items, value)
+	default:
+		return fmt.Errorf("index %d out of range for %s with %d elements", index, name, len(items))
+	}
+	*to.stringVal = strings.Join(items, ",")
+
+	return nil
+}
+
+// parseSetKey splits the key of a --set override into the parameter name and
+// the list index, which is -1 if there is none.
+func parseSetKey(key string) (name string, index int, err error) {
+	name, rest, ok := strings.Cut(key, "[")
+	if !ok {
+		return key, -1, nil
+	}
+
+	rest, ok = strings.CutSuffix(rest, "]")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid index in %s", key)
+	}
+
+	index, err = strconv.Atoi(rest)
+	if err != nil || index < 0 {
+		return "", 0, fmt.Errorf("invalid index in %s", key)
+	}
+
+	return name, index, nil
+}
+
+// undefined returns the error for an unknown flag. Like the flag package, it
+// returns flag.ErrHelp for -h and --help unless they are defined.
+func (p *FlagProvider) undefined(flagName string) error {
+	switch flagName {
+	case "-h", "-help", "--help":
+		return flag.ErrHelp
+	}
+
+	return errors.New("flag provided but not defined: " + flagName)
+}
+
 func (p *FlagProvider) normalizeName(name string) string {
 	return strings.ReplaceAll(name, "_", "-")
 }
+
+// takesValue reports whether the next argument in rest is the value of a flag
+// of type to. An int flag does not take a flag as its value, so that e.g.
+// "-p --name x" fails instead of parsing "--name" as a number.
+func takesValue(to typ, rest []string) bool {
+	if len(rest) == 0 {
+		return false
+	}
+
+	next := rest[0]
+	return to.kind != reflect.Int || len(next) < 2 || next[0] != '-' || isInt(next)
+}
+
+func isInt(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
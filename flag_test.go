@@ -0,0 +1,345 @@
+package conf_test
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFlagProviderGNU(t *testing.T) {
+	type flags struct {
+		Port    int    `conf:"port,default=8080" short:"p"`
+		Host    string `conf:"host" short:"H"`
+		Verbose int    `conf:"verbose,count" short:"v"`
+		Feature bool   `conf:"feature,default=true"`
+		Debug   bool   `conf:"debug" short:"d"`
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		want      flags
+		remaining []string
+	}{
+		{
+			name: "long with equals",
+			args: []string{"--port=80", "--host=example.com"},
+			want: flags{Port: 80, Host: "example.com", Feature: true},
+		},
+		{
+			name: "long with separate value",
+			args: []string{"--port", "80"},
+			want: flags{Port: 80, Feature: true},
+		},
+		{
+			name: "single dash long",
+			args: []string{"-port", "80", "-host=example.com"},
+			want: flags{Port: 80, Host: "example.com", Feature: true},
+		},
+		{
+			name: "short with separate value",
+			args: []string{"-p", "80", "-H", "example.com"},
+			want: flags{Port: 80, Host: "example.com", Feature: true},
+		},
+		{
+			name: "short with attached value",
+			args: []string{"-p80", "-Hexample.com"},
+			want: flags{Port: 80, Host: "example.com", Feature: true},
+		},
+		{
+			name: "negative value",
+			args: []string{"-p", "-1", "--verbose", "-2"},
+			want: flags{Port: -1, Verbose: -2, Feature: true},
+		},
+		{
+			name: "counting",
+			args: []string{"-vvv"},
+			want: flags{Port: 8080, Verbose: 3, Feature: true},
+		},
+		{
+			name: "bundled",
+			args: []string{"-dvv", "-v"},
+			want: flags{Port: 8080, Verbose: 3, Feature: true, Debug: true},
+		},
+		{
+			name: "negated bool",
+			args: []string{"--no-feature"},
+			want: flags{Port: 8080},
+		},
+		{
+			name:      "terminator",
+			args:      []string{"-d", "--", "-p", "80"},
+			want:      flags{Port: 8080, Feature: true, Debug: true},
+			remaining: []string{"-p", "80"},
+		},
+		{
+			name:      "stops at first argument",
+			args:      []string{"-d", "subcommand", "-p", "80"},
+			want:      flags{Port: 8080, Feature: true, Debug: true},
+			remaining: []string{"subcommand", "-p", "80"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg flags
+			var remaining []string
+			if err := conf.LoadFlagsWithRemaining(&cfg, tt.args, func(r []string) {
+				remaining = r
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, cfg); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.remaining, remaining); diff != "" {
+				t.Errorf("unexpected remaining args (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFlagProviderErrors(t *testing.T) {
+	type flags struct {
+		Port int    `conf:"port" short:"p"`
+		Name string `conf:"name"`
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "undefined long",
+			args: []string{"--unknown"},
+			want: "failed to load configuration: failed to load configuration with *conf.FlagProvider: failed to parse flags: flag provided but not defined: --unknown",
+		},
+		{
+			name: "undefined short",
+			args: []string{"-x"},
+			want: "failed to load configuration: failed to load configuration with *conf.FlagProvider: failed to parse flags: flag provided but not defined: -x",
+		},
+		{
+			name: "missing argument",
+			args: []string{"--port"},
+			want: "failed to load configuration: failed to load configuration with *conf.FlagProvider: failed to parse flags: flag needs an argument: --port",
+		},
+		{
+			name: "missing short argument",
+			args: []string{"-p", "--name", "x"},
+			want: "failed to load configuration: failed to load configuration with *conf.FlagProvider: failed to parse flags: flag needs an argument: -p",
+		},
+		{
+			name: "short int without count option",
+			args: []string{"-pp"},
+			want: `failed to load configuration: failed to load configuration with *conf.FlagProvider: failed to parse flags: invalid value "p" for flag -p: strconv.Atoi: parsing "p": invalid syntax`,
+		},
+		{
+			name: "invalid value",
+			args: []string{"-p=abc"},
+			want: `failed to load configuration: failed to load configuration with *conf.FlagProvider: failed to parse flags: invalid value "abc" for flag -p: strconv.Atoi: parsing "abc": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg flags
+			err := conf.LoadFlags(&cfg, tt.args)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("count option on string", func(t *testing.T) {
+		var cfg struct {
+			Name string `conf:"name,count" short:"n"`
+		}
+		err := conf.LoadFlags(&cfg, nil)
+		if err == nil || err.Error() != `field Name: option "count" needs an int field, got string` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("list option on int", func(t *testing.T) {
+		var cfg struct {
+			Port int `conf:"port,list"`
+		}
+		err := conf.LoadFlags(&cfg, nil)
+		if err == nil || err.Error() != `field Port: option "list" needs a string field, got int` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("duplicate short", func(t *testing.T) {
+		var cfg struct {
+			Port int    `conf:"port" short:"p"`
+			Path string `conf:"path" short:"p"`
+		}
+		err := conf.LoadFlags(&cfg, []string{"-p", "80"})
+		if err == nil || err.Error() != `short name "p" of path is already used by port` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var cfg flags
+		if err := conf.LoadFlags(&cfg, []string{"--help"}); !errors.Is(err, flag.ErrHelp) {
+			t.Fatalf("expected flag.ErrHelp, got %v", err)
+		}
+	})
+}
+
+func TestFlagProviderSet(t *testing.T) {
+	type flags struct {
+		Brokers string `conf:"brokers,default=a,list"`
+		DSN     string `conf:"dsn,default='host=db,port=5432'"`
+		DB      struct {
+			Host string `conf:"host"`
+		} `conf:"db"`
+		Feature struct {
+			X bool `conf:"x"`
+		} `conf:"feature"`
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    flags
+		wantErr string
+	}{
+		{
+			name: "nested",
+			args: []string{"-set", "db.host=foo", "--set=feature.x=true"},
+			want: func() flags {
+				var f flags
+				f.Brokers = "a"
+				f.DSN = "host=db,port=5432"
+				f.DB.Host = "foo"
+				f.Feature.X = true
+				return f
+			}(),
+		},
+		{
+			name: "list",
+			args: []string{"--set", "brokers=c,d"},
+			want: flags{Brokers: "c,d", DSN: "host=db,port=5432"},
+		},
+		{
+			name: "list element",
+			args: []string{"--set", "brokers[1]=x", "--set", "brokers[2]=y"},
+			want: flags{Brokers: "a,x,y", DSN: "host=db,port=5432"},
+		},
+		{
+			name:    "unknown parameter",
+			args:    []string{"--set", "db.hots=foo"},
+			wantErr: `invalid value "db.hots=foo" for flag --set: unknown parameter db.hots`,
+		},
+		{
+			name:    "index out of range",
+			args:    []string{"--set", "brokers[2]=x"},
+			wantErr: `invalid value "brokers[2]=x" for flag --set: index 2 out of range for brokers with 1 elements`,
+		},
+		{
+			name:    "not a list",
+			args:    []string{"--set", "feature.x[0]=true"},
+			wantErr: `invalid value "feature.x[0]=true" for flag --set: parameter feature.x is not a list`,
+		},
+		{
+			name:    "string without list option",
+			args:    []string{"--set", "dsn[1]=port=5433"},
+			wantErr: `invalid value "dsn[1]=port=5433" for flag --set: parameter dsn is not a list`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg flags
+			err := conf.LoadFlags(&cfg, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, cfg); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFlagProviderArgs(t *testing.T) {
+	type copyConfig struct {
+		Force bool     `conf:"force" short:"f"`
+		Src   string   `conf:"src,required" arg:"0"`
+		Count int      `conf:"count,default=1" arg:"1"`
+		Files []string `arg:"rest"`
+	}
+
+	t.Run("bind", func(t *testing.T) {
+		var cfg copyConfig
+		var remaining []string
+		if err := conf.LoadFlagsWithRemaining(&cfg, []string{"-f", "a.txt", "3", "b.txt", "c.txt"}, func(r []string) {
+			remaining = r
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := copyConfig{Force: true, Src: "a.txt", Count: 3, Files: []string{"b.txt", "c.txt"}}
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+
+		if diff := cmp.Diff([]string{"a.txt", "3", "b.txt", "c.txt"}, remaining); diff != "" {
+			t.Errorf("unexpected remaining args (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		var cfg copyConfig
+		if err := conf.LoadFlags(&cfg, []string{"a.txt"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := copyConfig{Src: "a.txt", Count: 1}
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var cfg copyConfig
+		err := conf.LoadFlags(&cfg, []string{"-f"})
+		if err == nil || err.Error() != "missing configuration parameters: src" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		var cfg copyConfig
+		err := conf.LoadFlags(&cfg, []string{"a.txt", "many"})
+		if err == nil || !strings.Contains(err.Error(), `invalid value "many" for argument count`) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		var cfg struct {
+			Files []string `arg:"0"`
+		}
+		err := conf.LoadFlags(&cfg, nil)
+		if err == nil || err.Error() != "field Files: positional argument has unsupported type []string" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package conf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// usageTagName is the struct tag holding a human readable description of a
+// configuration parameter, e.g. `usage:"port to listen on"`.
+const usageTagName = "usage"
+
+// GenerateOption configures GenerateDotEnv and GenerateMarkdown.
+type GenerateOption func(*generateConfig)
+
+type generateConfig struct {
+	envPrefix string
+}
+
+// WithEnvPrefix returns a GenerateOption that prepends prefix to every
+// environment variable, like EnvProvider.WithPrefix, e.g. MYAPP_PORT for the
+// parameter "port" with the prefix "MYAPP_".
+func WithEnvPrefix(prefix string) GenerateOption {
+	return func(c *generateConfig) {
+		c.envPrefix = prefix
+	}
+}
+
+// newGenerateConfig applies opts to the default configuration.
+func newGenerateConfig(opts []GenerateOption) *generateConfig {
+	c := &generateConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GenerateDotEnv writes an example .env file for the struct cfg points to.
+// Every environment variable is written with its default value, and its
+// usage, type and whether it is required are written as comments above it.
+func GenerateDotEnv(w io.Writer, cfg any, opts ...GenerateOption) error {
+	s, err := compileConfig(cfg)
+	if err != nil {
+		return err
+	}
+	c := newGenerateConfig(opts)
+
+	for i, f := range s.options() {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		if f.usage != "" {
+			if _, err := fmt.Fprintf(w, "# %s\n", f.usage); err != nil {
+				return err
+			}
+		}
+
+		details := "Type: " + f.kind.String() + "."
+		if f.required {
+			details += " Required."
+		}
+		if f.fallback != "" {
+			details += " Default: " + f.fallback + "."
+		}
+
+		if _, err := fmt.Fprintf(w, "# %s\n%s=%s\n", details, c.envPrefix+envName(f.name), f.fallback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateMarkdown writes a Markdown table documenting the environment
+// variables of the struct cfg points to.
+func GenerateMarkdown(w io.Writer, cfg any, opts ...GenerateOption) error {
+	s, err := compileConfig(cfg)
+	if err != nil {
+		return err
+	}
+	c := newGenerateConfig(opts)
+
+	if _, err := fmt.Fprint(w, "| Variable | Type | Default | Required | Description |\n"+
+		"| --- | --- | --- | --- | --- |\n"); err != nil {
+		return err
+	}
+
+	for _, f := range s.options() {
+		fallback := ""
+		if f.fallback != "" {
+			fallback = "`" + f.fallback + "`"
+		}
+
+		required := "no"
+		if f.required {
+			required = "yes"
+		}
+
+		if _, err := fmt.Fprintf(w, "| `%s` | %s | %s | %s | %s |\n",
+			c.envPrefix+envName(f.name), f.kind, fallback, required, markdownEscape(f.usage)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markdownEscape escapes s for use in a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
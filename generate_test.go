@@ -0,0 +1,122 @@
+package conf_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type generateConfig struct {
+	Host string `conf:"host,required" usage:"host to listen on"`
+	Port int    `conf:"port,default=8080" usage:"port to listen on"`
+
+	Nested struct {
+		Debug bool `conf:"debug-mode"`
+	}
+
+	UntaggedField string
+}
+
+func TestGenerateDotEnv(t *testing.T) {
+	var b strings.Builder
+	if err := conf.GenerateDotEnv(&b, &generateConfig{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `# host to listen on
+# Type: string. Required.
+HOST=
+
+# port to listen on
+# Type: int. Default: 8080.
+PORT=8080
+
+# Type: bool.
+DEBUG_MODE=
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := conf.GenerateMarkdown(&b, &generateConfig{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "| Variable | Type | Default | Required | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| `HOST` | string |  | yes | host to listen on |\n" +
+		"| `PORT` | int | `8080` | no | port to listen on |\n" +
+		"| `DEBUG_MODE` | bool |  | no |  |\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateInvalidType(t *testing.T) {
+	var str string
+	err := conf.GenerateDotEnv(&strings.Builder{}, &str)
+	if err == nil || err.Error() != "expected pointer to struct, got pointer to string" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerateInvalidTags(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  any
+		want string
+	}{
+		{
+			name: "misspelled option",
+			cfg: &struct {
+				Host string `conf:"host,requried"`
+			}{},
+			want: `field Host: invalid conf tag: unknown option "requried", did you mean "required"?`,
+		},
+		{
+			name: "invalid fallback",
+			cfg: &struct {
+				Port int `conf:"port,default=abc"`
+			}{},
+			want: `failed to parse fallback value "abc" as int: strconv.Atoi: parsing "abc": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := conf.GenerateDotEnv(&b, tt.cfg); err == nil || err.Error() != tt.want {
+				t.Errorf("unexpected error from GenerateDotEnv: %v", err)
+			}
+			if err := conf.GenerateMarkdown(&b, tt.cfg); err == nil || err.Error() != tt.want {
+				t.Errorf("unexpected error from GenerateMarkdown: %v", err)
+			}
+			if b.Len() > 0 {
+				t.Errorf("unexpected output: %q", b.String())
+			}
+		})
+	}
+}
+
+func TestGenerateWithEnvPrefix(t *testing.T) {
+	var dotenv strings.Builder
+	if err := conf.GenerateDotEnv(&dotenv, &generateConfig{}, conf.WithEnvPrefix("MYAPP_")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(dotenv.String(), "\nMYAPP_PORT=8080\n") {
+		t.Errorf("missing prefixed variable in:\n%s", dotenv.String())
+	}
+
+	var markdown strings.Builder
+	if err := conf.GenerateMarkdown(&markdown, &generateConfig{}, conf.WithEnvPrefix("MYAPP_")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(markdown.String(), "| `MYAPP_PORT` | int |") {
+		t.Errorf("missing prefixed variable in:\n%s", markdown.String())
+	}
+}
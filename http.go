@@ -0,0 +1,275 @@
+//go:build !tinygo
+
+package conf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/solhall/conf/dotenv"
+)
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	defaultHTTPBackoff = 500 * time.Millisecond
+)
+
+var _ Provider = (*HTTPProvider)(nil)
+
+// HTTPProvider reads configuration from a JSON or dotenv document fetched over
+// HTTP(S). A document starting with "{" is parsed as JSON, where the values of
+// nested objects are named like nested structs, e.g. "db.host". Otherwise it
+// is parsed as a .env file with upper snake case names, like EnvProvider.
+//
+// The document is cached with its ETag, and only fetched again when it
+// changed. If the server cannot be reached or fails with a status worth
+// retrying, i.e. 5xx or 429, the last document fetched is used, which can be
+// kept on disk with WithCacheFile. Other statuses, like 401 or 404 for revoked
+// credentials or a wrong URL, make Load fail.
+type HTTPProvider struct {
+	url       string
+	client    *http.Client
+	timeout   time.Duration
+	retries   int
+	backoff   time.Duration
+	cacheFile string
+	// onCacheError is called when the cache file cannot be written.
+	onCacheError func(err error)
+
+	// etag and body are the last document fetched.
+	etag string
+	body []byte
+
+	lookupVars
+}
+
+// NewHTTPProvider creates a new HTTPProvider that fetches the document at url.
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{
+		url:        url,
+		client:     http.DefaultClient,
+		timeout:    defaultHTTPTimeout,
+		backoff:    defaultHTTPBackoff,
+		lookupVars: newLookupVars(),
+	}
+}
+
+// WithClient sets the client used to fetch the document. The default is
+// http.DefaultClient.
+func (p *HTTPProvider) WithClient(client *http.Client) *HTTPProvider {
+	p.client = client
+	return p
+}
+
+// WithTimeout sets the timeout of each request. The default is ten seconds.
+func (p *HTTPProvider) WithTimeout(d time.Duration) *HTTPProvider {
+	p.timeout = d
+	return p
+}
+
+// WithRetries sets how often a failed request is retried. The delay before
+// each retry starts at backoff and doubles every time.
+func (p *HTTPProvider) WithRetries(retries int, backoff time.Duration) *HTTPProvider {
+	p.retries = retries
+	p.backoff = backoff
+	return p
+}
+
+// WithCacheFile sets a file the last document fetched is written to, and read
+// from if the server cannot be reached.
+func (p *HTTPProvider) WithCacheFile(path string) *HTTPProvider {
+	p.cacheFile = path
+	return p
+}
+
+// WithCacheErrorFunc sets a function that is called with the error if the
+// document fetched cannot be written to the cache file. Such errors do not make
+// Load fail, since the document was fetched. By default they are ignored.
+func (p *HTTPProvider) WithCacheErrorFunc(f func(err error)) *HTTPProvider {
+	p.onCacheError = f
+	return p
+}
+
+// Load fetches the document and reads the configuration from it.
+func (p *HTTPProvider) Load() error {
+	body, retryable, err := p.fetchWithRetries()
+	if err != nil {
+		if !retryable {
+			return err
+		}
+
+		body, err = p.lastKnownGood(err)
+		if err != nil {
+			return err
+		}
+	}
+
+	values, lookupName, err := parseDocument(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", p.url, err)
+	}
+
+	return p.load(func(name string) (string, error) {
+		return values[lookupName(name)], nil
+	})
+}
+
+// fetchWithRetries fetches the document, retrying failed requests. If it
+// fails, it reports whether the last request failed in a way worth retrying.
+func (p *HTTPProvider) fetchWithRetries() (body []byte, retryable bool, err error) {
+	backoff := p.backoff
+
+	var errs []error
+	for attempt := 0; ; attempt++ {
+		body, retry, err := p.fetch()
+		if err == nil {
+			return body, false, nil
+		}
+
+		errs = append(errs, err)
+		if !retry || attempt >= p.retries {
+			return nil, retry, fmt.Errorf("failed to fetch %s: %w", p.url, errors.Join(errs...))
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// fetch requests the document once. It reports whether a failed request
+// should be retried.
+func (p *HTTPProvider) fetch() (body []byte, retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if p.etag != "" && p.body != nil {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && p.body != nil:
+		return p.body, false, nil
+	case resp.StatusCode != http.StatusOK:
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	p.etag = resp.Header.Get("ETag")
+	p.body = body
+
+	if p.cacheFile != "" {
+		if err := writeFileAtomic(p.cacheFile, body); err != nil && p.onCacheError != nil {
+			p.onCacheError(fmt.Errorf("failed to write cache file: %w", err))
+		}
+	}
+
+	return body, false, nil
+}
+
+// lastKnownGood returns the last document fetched, from memory or the cache
+// file, or fetchErr if there is none.
+func (p *HTTPProvider) lastKnownGood(fetchErr error) ([]byte, error) {
+	if p.body != nil {
+		return p.body, nil
+	}
+
+	if p.cacheFile != "" {
+		body, err := os.ReadFile(p.cacheFile)
+		if err == nil {
+			return body, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, errors.Join(fetchErr, fmt.Errorf("failed to read cache file: %w", err))
+		}
+	}
+
+	return nil, fetchErr
+}
+
+// parseDocument parses a JSON or dotenv document into values, and returns the
+// function mapping parameter names to their keys in values.
+func parseDocument(body []byte) (map[string]string, func(string) string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		values, err := dotenv.ParseReader(bytes.NewReader(body))
+		return values, envName, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string)
+	flattenJSON(values, "", doc)
+
+	return values, func(name string) string { return name }, nil
+}
+
+// flattenJSON adds the values of the JSON object doc to values, with the keys
+// of nested objects joined by ".".
+func flattenJSON(values map[string]string, prefix string, doc map[string]any) {
+	for key, value := range doc {
+		switch value := value.(type) {
+		case map[string]any:
+			flattenJSON(values, prefix+key+".", value)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[prefix+key] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[prefix+key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to path, so
+// that readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,165 @@
+//go:build !tinygo
+
+package conf_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type httpConfig struct {
+	Port int `conf:"port"`
+	DB   struct {
+		Host string `conf:"host,required"`
+	} `conf:"db"`
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var requests, notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"port": 80, "db": {"host": "db.example.com"}}`))
+	}))
+	defer srv.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "config.json")
+	provider := conf.NewHTTPProvider(srv.URL).WithCacheFile(cacheFile)
+
+	var want httpConfig
+	want.Port = 80
+	want.DB.Host = "db.example.com"
+
+	for i := 0; i < 2; i++ {
+		var cfg httpConfig
+		if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	}
+
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("expected 2 requests with 1 cache hit, got %d with %d", requests.Load(), notModified.Load())
+	}
+
+	t.Run("last known good", func(t *testing.T) {
+		srv.Close()
+
+		var cfg httpConfig
+		provider := conf.NewHTTPProvider(srv.URL).WithCacheFile(cacheFile)
+		if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("no fallback on client errors", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+
+		var cfg httpConfig
+		provider := conf.NewHTTPProvider(srv.URL).WithCacheFile(cacheFile)
+		err := conf.Load(&cfg, conf.WithProviders(provider))
+		want := "failed to load configuration: failed to load configuration with *conf.HTTPProvider: failed to fetch " + srv.URL + ": unexpected status 401 Unauthorized"
+		if err == nil || err.Error() != want {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("cache file not writable", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"port": 80, "db": {"host": "db.example.com"}}`))
+		}))
+		defer srv.Close()
+
+		var cacheErr error
+		cacheFile := filepath.Join(t.TempDir(), "missing", "config.json")
+		provider := conf.NewHTTPProvider(srv.URL).WithCacheFile(cacheFile).WithCacheErrorFunc(func(err error) {
+			cacheErr = err
+		})
+
+		var cfg httpConfig
+		if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+
+		if cacheErr == nil || !strings.HasPrefix(cacheErr.Error(), "failed to write cache file: ") {
+			t.Errorf("unexpected cache error: %v", cacheErr)
+		}
+	})
+
+	t.Run("unreachable without cache", func(t *testing.T) {
+		var cfg httpConfig
+		err := conf.Load(&cfg, conf.WithProviders(conf.NewHTTPProvider(srv.URL)))
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestHTTPProviderRetries(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("PORT=80\nDB_HOST=db.example.com\n"))
+	}))
+	defer srv.Close()
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		var cfg httpConfig
+		provider := conf.NewHTTPProvider(srv.URL).WithRetries(2, time.Millisecond)
+		if err := conf.Load(&cfg, conf.WithProviders(provider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.Port != 80 || cfg.DB.Host != "db.example.com" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		requests.Store(0)
+
+		var cfg httpConfig
+		provider := conf.NewHTTPProvider(srv.URL).WithRetries(1, time.Millisecond)
+		err := conf.Load(&cfg, conf.WithProviders(provider))
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+
+		if requests.Load() != 2 {
+			t.Errorf("expected 2 requests, got %d", requests.Load())
+		}
+	})
+}
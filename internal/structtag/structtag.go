@@ -0,0 +1,99 @@
+// Package structtag parses the `conf` struct tag, for conf and for the loaders
+// generated by confgen.
+package structtag
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/solhall/conf/internal/suggest"
+)
+
+// Tag is a parsed `conf` tag.
+type Tag struct {
+	Name     string
+	Required bool
+	Fallback string
+	// Options are the options other than "required" and "default", e.g.
+	// "static".
+	Options []string
+}
+
+// OptionNames are the options a `conf` tag may have besides "default".
+var OptionNames = []string{"required", "static", "file", "count", "list"}
+
+// Parse parses a `conf` tag, e.g. "field1,default=my value,required". The
+// parts are separated by commas. A value can be quoted with single quotes to
+// contain commas, e.g. "brokers,default='a,b'". A backslash escapes a comma,
+// quote or backslash, also within quotes, and any other backslash is an error,
+// so that values like "C:\tmp" are not silently changed.
+func Parse(tag string) (Tag, error) {
+	parts, err := split(tag)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	t := Tag{Name: parts[0]}
+	for _, part := range parts[1:] {
+		key, value, hasValue := strings.Cut(part, "=")
+		switch {
+		case key == "default" && hasValue:
+			t.Fallback = value
+		case key == "default":
+			return Tag{}, errors.New(`option "default" needs a value`)
+		case !slices.Contains(OptionNames, key):
+			if suggestion := suggest.Closest(key, append(slices.Clone(OptionNames), "default")); suggestion != "" {
+				return Tag{}, fmt.Errorf("unknown option %q, did you mean %q?", key, suggestion)
+			}
+			return Tag{}, fmt.Errorf("unknown option %q", key)
+		case hasValue:
+			return Tag{}, fmt.Errorf("option %q does not take a value", key)
+		case key == "required":
+			t.Required = true
+		default:
+			t.Options = append(t.Options, key)
+		}
+	}
+
+	return t, nil
+}
+
+// split splits a `conf` tag at the commas outside of quotes, and removes the
+// quotes and escaping backslashes.
+func split(tag string) ([]string, error) {
+	var (
+		parts   []string
+		part    strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range tag {
+		switch {
+		case escaped && !strings.ContainsRune(`,'\`, r):
+			return nil, fmt.Errorf(`invalid escape "\%c", only \, \' and \\ can be escaped`, r)
+		case escaped:
+			part.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, errors.New("trailing backslash")
+	case quoted:
+		return nil, errors.New("unterminated quote")
+	}
+
+	return append(parts, part.String()), nil
+}
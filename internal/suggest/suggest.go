@@ -0,0 +1,38 @@
+// Package suggest finds likely corrections for misspelled names.
+package suggest
+
+// Closest returns the candidate closest to name by edit distance, or an empty
+// string if none is close enough to be a likely typo.
+func Closest(name string, candidates []string) string {
+	best, bestDist := "", len(name)/3+1
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
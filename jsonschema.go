@@ -0,0 +1,114 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaDraft identifies the JSON Schema version written by JSONSchema.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchema is a JSON Schema of a configuration parameter or of an object
+// holding parameters.
+type jsonSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        string                 `json:"type"`
+	Default     any                    `json:"default,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Minimum     *int                   `json:"minimum,omitempty"`
+	Maximum     *int                   `json:"maximum,omitempty"`
+	Properties  map[string]*jsonSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) of the struct cfg points
+// to, e.g. to validate and autocomplete configuration files in editors.
+// Every parameter is a property with its type, default, validation tags and
+// its usage as description, and nested names like "db.host" are nested
+// objects. Positional arguments are left out.
+func JSONSchema(cfg any) ([]byte, error) {
+	s, err := compileConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &jsonSchema{
+		Schema: jsonSchemaDraft,
+		Title:  s.typ.Name(),
+		Type:   "object",
+	}
+	for _, f := range s.options() {
+		if err := root.add(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// add adds the parameter f as a property, creating the objects of its dotted
+// name.
+func (s *jsonSchema) add(f schemaField) error {
+	path := strings.Split(f.name, ".")
+	for i, name := range path[:len(path)-1] {
+		child, ok := s.Properties[name]
+		switch {
+		case !ok:
+			child = &jsonSchema{Type: "object"}
+			s.setProperty(name, child)
+		case child.Type != "object":
+			return fmt.Errorf("parameter %s conflicts with parameter %s", f.name, strings.Join(path[:i+1], "."))
+		}
+		s = child
+	}
+
+	name := path[len(path)-1]
+	if prop, ok := s.Properties[name]; ok {
+		if prop.Type == "object" {
+			return fmt.Errorf("parameter %s conflicts with the parameters nested in it", f.name)
+		}
+		return fmt.Errorf("parameter %s is declared twice", f.name)
+	}
+
+	prop := &jsonSchema{
+		Description: f.usage,
+		Enum:        f.validation.enum,
+		Minimum:     f.validation.min,
+		Maximum:     f.validation.max,
+	}
+	switch f.kind {
+	case reflect.Int:
+		prop.Type = "integer"
+		if f.fallback != "" {
+			prop.Default = f.fallbackInt
+		}
+	case reflect.Bool:
+		prop.Type = "boolean"
+		if f.fallback != "" {
+			prop.Default = f.fallbackBool
+		}
+	default:
+		prop.Type = "string"
+		if f.fallback != "" {
+			prop.Default = f.fallback
+		}
+	}
+
+	s.setProperty(name, prop)
+	if f.required {
+		s.Required = append(s.Required, name)
+	}
+
+	return nil
+}
+
+func (s *jsonSchema) setProperty(name string, prop *jsonSchema) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*jsonSchema)
+	}
+	s.Properties[name] = prop
+}
@@ -0,0 +1,98 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type schemaConfig struct {
+	Host  string `conf:"host,required" usage:"host to listen on"`
+	Port  int    `conf:"port,default=8080" usage:"port to listen on" min:"1" max:"65535"`
+	Level string `conf:"log-level,default=info" enum:"debug,info,warn"`
+
+	DB struct {
+		User  string `conf:"user,required"`
+		Debug bool   `conf:"debug,default=false"`
+	} `conf:"db"`
+
+	File string `arg:"0"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	got, err := conf.JSONSchema(&schemaConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "schemaConfig",
+  "type": "object",
+  "properties": {
+    "db": {
+      "type": "object",
+      "properties": {
+        "debug": {
+          "type": "boolean",
+          "default": false
+        },
+        "user": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "user"
+      ]
+    },
+    "host": {
+      "description": "host to listen on",
+      "type": "string"
+    },
+    "log-level": {
+      "type": "string",
+      "default": "info",
+      "enum": [
+        "debug",
+        "info",
+        "warn"
+      ]
+    },
+    "port": {
+      "description": "port to listen on",
+      "type": "integer",
+      "default": 8080,
+      "minimum": 1,
+      "maximum": 65535
+    }
+  },
+  "required": [
+    "host"
+  ]
+}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("unexpected schema (-want +got):\n%s", diff)
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		var cfg struct {
+			DB   string `conf:"db"`
+			Host string `conf:"db.host"`
+		}
+		if _, err := conf.JSONSchema(&cfg); err == nil || err.Error() != "parameter db.host conflicts with parameter db" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		var cfg struct {
+			Port int `conf:"port,default=abc"`
+		}
+		_, err := conf.JSONSchema(&cfg)
+		if err == nil || err.Error() != `failed to parse fallback value "abc" as int: strconv.Atoi: parsing "abc": invalid syntax` {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// KVSource is a hierarchical key-value store like etcd or Consul. Keys are
+// paths separated by "/", e.g. "/app/db/host". Adapters for real stores live
+// in their own modules, so that conf does not depend on their clients.
+type KVSource interface {
+	// Get returns all keys starting with prefix and their values.
+	Get(prefix string) (map[string][]byte, error)
+	// Watch returns a channel that receives a value whenever a key starting
+	// with prefix changes, until ctx is done.
+	Watch(ctx context.Context, prefix string) (<-chan struct{}, error)
+}
+
+// Notifier is implemented by providers whose source can report changes, so
+// that Watch reloads the configuration when they happen.
+type Notifier interface {
+	// Notify returns a channel that receives a value whenever the source
+	// changes, until ctx is done.
+	Notify(ctx context.Context) (<-chan struct{}, error)
+}
+
+var (
+	_ Provider = (*KVProvider)(nil)
+	_ Notifier = (*KVProvider)(nil)
+)
+
+// KVProvider reads configuration from the keys below a prefix in a KVSource.
+// The rest of the key is the name of the parameter, with the fields of nested
+// structs in subpaths, e.g. with the prefix "/app/", "db.host" is read from
+// "/app/db/host".
+type KVProvider struct {
+	src    KVSource
+	prefix string
+	lookupVars
+}
+
+// NewKVProvider creates a new KVProvider that reads the keys below prefix
+// from src.
+func NewKVProvider(src KVSource, prefix string) *KVProvider {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &KVProvider{
+		src:        src,
+		prefix:     prefix,
+		lookupVars: newLookupVars(),
+	}
+}
+
+// Load reads the configuration from the source.
+func (p *KVProvider) Load() error {
+	kvs, err := p.src.Get(p.prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", p.prefix, err)
+	}
+
+	return p.load(func(name string) (string, error) {
+		return string(kvs[p.prefix+strings.ReplaceAll(name, ".", "/")]), nil
+	})
+}
+
+// Notify watches the keys below the prefix.
+func (p *KVProvider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	return p.src.Watch(ctx, p.prefix)
+}
+
+var _ KVSource = (*MemoryKV)(nil)
+
+// MemoryKV is an in-memory KVSource, e.g. for tests.
+type MemoryKV struct {
+	mu       sync.Mutex
+	kvs      map[string][]byte
+	watchers map[chan struct{}]string
+}
+
+// NewMemoryKV creates an empty MemoryKV.
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{
+		kvs:      make(map[string][]byte),
+		watchers: make(map[chan struct{}]string),
+	}
+}
+
+// Set sets the value of key.
+func (kv *MemoryKV) Set(key string, value []byte) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.kvs[key] = value
+	kv.notify(key)
+}
+
+// Delete deletes key.
+func (kv *MemoryKV) Delete(key string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	delete(kv.kvs, key)
+	kv.notify(key)
+}
+
+func (kv *MemoryKV) Get(prefix string) (map[string][]byte, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kvs := make(map[string][]byte)
+	for key, value := range kv.kvs {
+		if strings.HasPrefix(key, prefix) {
+			kvs[key] = value
+		}
+	}
+
+	return kvs, nil
+}
+
+func (kv *MemoryKV) Watch(ctx context.Context, prefix string) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+
+	kv.mu.Lock()
+	kv.watchers[ch] = prefix
+	kv.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		kv.mu.Lock()
+		defer kv.mu.Unlock()
+
+		delete(kv.watchers, ch)
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// notify signals the watchers of key. kv.mu must be held.
+func (kv *MemoryKV) notify(key string) {
+	for ch, prefix := range kv.watchers {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		// The channel is buffered, a pending signal covers this change.
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
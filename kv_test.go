@@ -0,0 +1,44 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type kvConfig struct {
+	Port int `conf:"port,default=8080"`
+	DB   struct {
+		Host string `conf:"host,required"`
+	} `conf:"db"`
+}
+
+func TestKVProvider(t *testing.T) {
+	kv := conf.NewMemoryKV()
+	kv.Set("/app/port", []byte("80"))
+	kv.Set("/app/db/host", []byte("db.example.com"))
+	kv.Set("/other/db/host", []byte("other.example.com"))
+
+	var cfg kvConfig
+	if err := conf.Load(&cfg, conf.WithProviders(conf.NewKVProvider(kv, "/app"))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want kvConfig
+	want.Port = 80
+	want.DB.Host = "db.example.com"
+
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		var cfg kvConfig
+		err := conf.Load(&cfg, conf.WithProviders(conf.NewKVProvider(conf.NewMemoryKV(), "/app/")))
+		if err == nil || err.Error() != "missing configuration parameters: db.host" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// lookupVars holds the parameters registered with a provider that looks up
+// values by name, like EnvProvider. A parameter without a value gets its
+// fallback value, and is missing if it is required and has no fallback.
+type lookupVars struct {
+	m         map[string]typ
+	fallbacks map[string]string
+	required  []string
+	// missing is a list of missing required configuration parameters. If a
+	// parameter does not have a value after considering the fallbacks map
+	// and it is required, it will be considered missing.
+	missing []string
+}
+
+func newLookupVars() lookupVars {
+	return lookupVars{
+		m:         make(map[string]typ),
+		fallbacks: make(map[string]string),
+		missing:   []string{},
+	}
+}
+
+func (v *lookupVars) StringVar(to *string, name, fallback string, required bool) {
+	v.m[name] = typ{kind: reflect.String, stringVal: to}
+	if fallback != "" {
+		v.fallbacks[name] = fallback
+	}
+	if required && !slices.Contains(v.required, name) {
+		v.required = append(v.required, name)
+	}
+}
+
+func (v *lookupVars) IntVar(to *int, name string, fallback int, required bool) {
+	v.m[name] = typ{kind: reflect.Int, intVal: to}
+	if fallback != 0 {
+		v.fallbacks[name] = fmt.Sprintf("%d", fallback)
+	}
+	if required && !slices.Contains(v.required, name) {
+		v.required = append(v.required, name)
+	}
+}
+
+func (v *lookupVars) BoolVar(to *bool, name string, fallback bool, required bool) {
+	v.m[name] = typ{kind: reflect.Bool, boolVal: to}
+	if fallback {
+		v.fallbacks[name] = "true"
+	}
+	if required && !slices.Contains(v.required, name) {
+		v.required = append(v.required, name)
+	}
+}
+
+func (v *lookupVars) Missing() []string {
+	return v.missing
+}
+
+// load sets every registered parameter to the value returned by lookup, or to
+// its fallback value if lookup returns an empty string.
+func (v *lookupVars) load(lookup func(name string) (string, error)) error {
+	v.missing = []string{}
+
+	for name, to := range v.m {
+		rawVal, err := lookup(name)
+		if err != nil {
+			return err
+		}
+
+		if rawVal == "" {
+			if fallback, ok := v.fallbacks[name]; ok {
+				rawVal = fallback
+			}
+		}
+
+		if rawVal == "" {
+			if slices.Contains(v.required, name) {
+				v.missing = append(v.missing, name)
+			}
+
+			continue
+		}
+
+		if err := to.set(rawVal); err != nil {
+			return fmt.Errorf("failed to parse %s as %s: %w", name, to.kind, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package conf
+
+import "maps"
+
+var _ Provider = (*MapProvider)(nil)
+
+// MapProvider reads configuration from a map, keyed by the names of the
+// parameters as in the `conf` tags, e.g. "db.host". It is useful for values
+// computed in code, like defaults based on runtime.NumCPU, layered with other
+// providers in WithProviders.
+type MapProvider struct {
+	values map[string]string
+	lookupVars
+}
+
+// NewMapProvider creates a new MapProvider that reads the parameters from
+// values. An empty value counts as unset, like with EnvProvider.
+func NewMapProvider(values map[string]string) *MapProvider {
+	return &MapProvider{
+		values:     maps.Clone(values),
+		lookupVars: newLookupVars(),
+	}
+}
+
+// Load sets the registered parameters from the map.
+func (p *MapProvider) Load() error {
+	return p.load(func(name string) (string, error) {
+		return p.values[name], nil
+	})
+}
@@ -0,0 +1,78 @@
+package conf_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMapProvider(t *testing.T) {
+	type mystruct struct {
+		Workers int    `conf:"workers,required"`
+		Debug   bool   `conf:"debug"`
+		Mode    string `conf:"mode,default=fast"`
+		DB      struct {
+			Host string `conf:"host,required"`
+		} `conf:"db"`
+	}
+
+	defaults := conf.NewMapProvider(map[string]string{
+		"workers": strconv.Itoa(4),
+		"db.host": "localhost",
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(defaults)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := mystruct{Workers: 4, Mode: "fast"}
+		want.DB.Host = "localhost"
+
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("layered", func(t *testing.T) {
+		env := env{"WORKERS": "8", "DEBUG": "true"}
+
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(defaults, conf.NewEnvProvider(env.Get))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := mystruct{Workers: 8, Debug: true, Mode: "fast"}
+		want.DB.Host = "localhost"
+
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("bool not overridden", func(t *testing.T) {
+		defaults := conf.NewMapProvider(map[string]string{"workers": "4", "debug": "true", "db.host": "localhost"})
+		env := env{}
+
+		var cfg mystruct
+		if err := conf.Load(&cfg, conf.WithProviders(defaults, conf.NewEnvProvider(env.Get))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !cfg.Debug {
+			t.Errorf("expected debug from the map to be kept, got %+v", cfg)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var cfg mystruct
+		err := conf.Load(&cfg, conf.WithProviders(conf.NewMapProvider(map[string]string{"workers": "4"})))
+		if err == nil || err.Error() != "missing configuration parameters: db.host" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
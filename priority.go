@@ -1,18 +1,28 @@
 package conf
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"slices"
 )
 
-var _ Provider = (*PriorityProvider)(nil)
+var (
+	_ ShortFlagProvider = (*PriorityProvider)(nil)
+	_ ArgProvider       = (*PriorityProvider)(nil)
+	_ TagOptionProvider = (*PriorityProvider)(nil)
+	_ FileSource        = (*PriorityProvider)(nil)
+	_ Notifier          = (*PriorityProvider)(nil)
+)
 
 type PriorityProvider struct {
 	m         map[string]typ
 	providers []Provider
 	required  []string
 	missing   []string
+	// args are the names of the positional arguments, which are only
+	// checked by the providers that bind them.
+	args []string
 }
 
 func NewPriorityProvider(providers ...Provider) *PriorityProvider {
@@ -29,7 +39,7 @@ func (p *PriorityProvider) StringVar(to *string, name, fallback string, required
 	}
 
 	p.m[name] = typ{kind: reflect.String, stringVal: to}
-	if required {
+	if required && !slices.Contains(p.required, name) {
 		p.required = append(p.required, name)
 	}
 }
@@ -40,7 +50,7 @@ func (p *PriorityProvider) IntVar(to *int, name string, fallback int, required b
 	}
 
 	p.m[name] = typ{kind: reflect.Int, intVal: to}
-	if required {
+	if required && !slices.Contains(p.required, name) {
 		p.required = append(p.required, name)
 	}
 }
@@ -51,12 +61,85 @@ func (p *PriorityProvider) BoolVar(to *bool, name string, fallback bool, require
 	}
 
 	p.m[name] = typ{kind: reflect.Bool, boolVal: to}
-	if required {
+	if required && !slices.Contains(p.required, name) {
 		p.required = append(p.required, name)
 	}
 }
 
+// ArgVar registers the positional argument with every provider that binds
+// positional arguments.
+func (p *PriorityProvider) ArgVar(to any, name string, index int, fallback string, required bool) {
+	for _, provider := range p.providers {
+		if provider, ok := provider.(ArgProvider); ok {
+			provider.ArgVar(to, name, index, fallback, required)
+		}
+	}
+
+	if !slices.Contains(p.args, name) {
+		p.args = append(p.args, name)
+	}
+}
+
+// TagOption sets the option with every provider that supports tag options.
+func (p *PriorityProvider) TagOption(name, option string) {
+	for _, provider := range p.providers {
+		if provider, ok := provider.(TagOptionProvider); ok {
+			provider.TagOption(name, option)
+		}
+	}
+}
+
+// ShortVar registers the alias with every provider that supports short names.
+func (p *PriorityProvider) ShortVar(name, short string) {
+	for _, provider := range p.providers {
+		if provider, ok := provider.(ShortFlagProvider); ok {
+			provider.ShortVar(name, short)
+		}
+	}
+}
+
+// Files returns the files read by the providers.
+func (p *PriorityProvider) Files() []string {
+	var files []string
+	for _, provider := range p.providers {
+		if provider, ok := provider.(FileSource); ok {
+			files = append(files, provider.Files()...)
+		}
+	}
+
+	return files
+}
+
+// Notify merges the notifications of the providers that report changes.
+func (p *PriorityProvider) Notify(ctx context.Context) (<-chan struct{}, error) {
+	merged := make(chan struct{}, 1)
+	for _, provider := range p.providers {
+		provider, ok := provider.(Notifier)
+		if !ok {
+			continue
+		}
+
+		ch, err := provider.Notify(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %T: %w", provider, err)
+		}
+
+		go func() {
+			for range ch {
+				select {
+				case merged <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	return merged, nil
+}
+
 func (p *PriorityProvider) Load() error {
+	p.missing = []string{}
+
 	for _, provider := range p.providers {
 		if err := provider.Load(); err != nil {
 			return fmt.Errorf("failed to load configuration with %T: %w", provider, err)
@@ -69,6 +152,18 @@ func (p *PriorityProvider) Load() error {
 		}
 	}
 
+	for _, provider := range p.providers {
+		if _, ok := provider.(ArgProvider); !ok {
+			continue
+		}
+
+		for _, name := range provider.Missing() {
+			if slices.Contains(p.args, name) && !slices.Contains(p.missing, name) {
+				p.missing = append(p.missing, name)
+			}
+		}
+	}
+
 	return nil
 }
 
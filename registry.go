@@ -0,0 +1,284 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/solhall/conf/internal/structtag"
+)
+
+// Registry holds parameters declared without a central struct, so that
+// packages can declare their own parameters. They are declared one by one like
+// with the flag package:
+//
+//	reg := conf.NewRegistry()
+//	port := conf.Int(reg, "port", 8080, conf.Short("p"))
+//	host := conf.String(reg, "db.host", "", conf.Required())
+//	if err := reg.Load(); err != nil { ... }
+//	fmt.Println(*host, *port)
+//
+// or as structs in a namespace with Registry.Register.
+type Registry struct {
+	params  []*param
+	structs []registeredStruct
+	// names maps the environment variable names of all parameters, which
+	// are the most normalized form of their names, to the parameters, to
+	// detect conflicts, e.g. between "db.host" and "db_host".
+	names map[string]string
+	// shorts maps the short names to the parameters using them.
+	shorts map[string]string
+}
+
+// registeredStruct is a struct registered with Registry.Register.
+type registeredStruct struct {
+	namespace string
+	v         reflect.Value
+	s         *schema
+}
+
+// DefaultRegistry is the Registry used by Register and LoadRegistered.
+var DefaultRegistry = NewRegistry()
+
+// Register registers cfg in the namespace with DefaultRegistry, see
+// Registry.Register.
+func Register(namespace string, cfg any) {
+	DefaultRegistry.Register(namespace, cfg)
+}
+
+// LoadRegistered loads the parameters of DefaultRegistry, see Registry.Load.
+func LoadRegistered(opts ...LoadOption) error {
+	return DefaultRegistry.Load(opts...)
+}
+
+// param is a parameter declared in a Registry.
+type param struct {
+	name     string
+	kind     reflect.Kind
+	required bool
+	short    string
+	options  []string
+	register func(p Provider, param *param)
+}
+
+// ParamOption configures a parameter declared in a Registry.
+type ParamOption func(*param)
+
+// Required makes the parameter required, like the "required" tag option.
+func Required() ParamOption {
+	return func(p *param) {
+		p.required = true
+	}
+}
+
+// Short sets a single letter alias for the flag, like the `short` tag.
+func Short(short string) ParamOption {
+	return func(p *param) {
+		p.short = short
+	}
+}
+
+// Option sets a tag option for providers implementing TagOptionProvider, e.g.
+// Option("file") like `conf:"db_password,file"`.
+func Option(option string) ParamOption {
+	return func(p *param) {
+		p.options = append(p.options, option)
+	}
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]string), shorts: make(map[string]string)}
+}
+
+// Register registers cfg, a pointer to a struct, whose fields are loaded like
+// with Load, with the namespace prepended to their names. E.g. the field
+// `conf:"host"` in the namespace "redis" is named "redis.host", and read from
+// REDIS_HOST by EnvProvider.
+//
+// Like the flag package, Register panics if cfg is not a pointer to a struct
+// with valid tags, or if one of its parameters or short names is already
+// declared. Names read from the same environment variable conflict as well,
+// e.g. "db.host" and "db_host", or the field "prefix" in the namespace
+// "cache-redis" and "cache.redis.prefix".
+func (r *Registry) Register(namespace string, cfg any) {
+	t, err := structType(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("conf: failed to register %s: %v", namespace, err))
+	}
+
+	prefix := namespace
+	if prefix != "" {
+		prefix += "."
+	}
+
+	s, err := compileStruct(t, prefix)
+	if err != nil {
+		panic(fmt.Sprintf("conf: failed to register %s: %v", namespace, err))
+	}
+
+	for _, f := range s.fields {
+		r.declare(f.name)
+		if f.short != "" {
+			r.declareShort(f.name, f.short)
+		}
+	}
+
+	r.structs = append(r.structs, registeredStruct{
+		namespace: namespace,
+		v:         reflect.ValueOf(cfg).Elem(),
+		s:         s,
+	})
+}
+
+// declare adds the name of a parameter, and panics if it is already declared
+// or would be read from the same environment variable or flag as another.
+func (r *Registry) declare(name string) {
+	key := envName(name)
+	switch declared, ok := r.names[key]; {
+	case ok && declared == name:
+		panic(fmt.Sprintf("conf: parameter %s declared twice", name))
+	case ok:
+		panic(fmt.Sprintf("conf: parameter %s conflicts with %s, both are read from %s", name, declared, key))
+	}
+	r.names[key] = name
+}
+
+// declareShort adds the short name of a parameter, and panics if another
+// parameter uses it already.
+func (r *Registry) declareShort(name, short string) {
+	if declared, ok := r.shorts[short]; ok {
+		panic(fmt.Sprintf("conf: short name %q of %s is already used by %s", short, name, declared))
+	}
+	r.shorts[short] = name
+}
+
+// String declares a string parameter and returns a pointer to its value,
+// which is set by Load.
+func String(r *Registry, name, fallback string, opts ...ParamOption) *string {
+	to := new(string)
+	r.add(name, reflect.String, opts, func(p Provider, param *param) {
+		*to = ""
+		p.StringVar(to, param.name, fallback, param.required)
+	})
+
+	return to
+}
+
+// Int declares an int parameter and returns a pointer to its value, which is
+// set by Load.
+func Int(r *Registry, name string, fallback int, opts ...ParamOption) *int {
+	to := new(int)
+	r.add(name, reflect.Int, opts, func(p Provider, param *param) {
+		*to = 0
+		p.IntVar(to, param.name, fallback, param.required)
+	})
+
+	return to
+}
+
+// Bool declares a bool parameter and returns a pointer to its value, which is
+// set by Load.
+func Bool(r *Registry, name string, fallback bool, opts ...ParamOption) *bool {
+	to := new(bool)
+	r.add(name, reflect.Bool, opts, func(p Provider, param *param) {
+		*to = false
+		p.BoolVar(to, param.name, fallback, param.required)
+	})
+
+	return to
+}
+
+// add adds a parameter. Like the flag package, it panics if the name or the
+// short name is already declared, or if an option is invalid.
+func (r *Registry) add(name string, kind reflect.Kind, opts []ParamOption, register func(Provider, *param)) {
+	p := &param{name: name, kind: kind, register: register}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.short != "" && len(p.short) != 1 {
+		panic(fmt.Sprintf("conf: short name %q of %s must be a single character", p.short, name))
+	}
+
+	for _, option := range p.options {
+		if !slices.Contains(structtag.OptionNames, option) {
+			panic(fmt.Sprintf("conf: unknown option %q of %s", option, name))
+		}
+		if option == "count" && kind != reflect.Int {
+			panic(fmt.Sprintf("conf: option \"count\" of %s needs an int parameter, got %s", name, kind))
+		}
+		if option == "list" && kind != reflect.String {
+			panic(fmt.Sprintf("conf: option \"list\" of %s needs a string parameter, got %s", name, kind))
+		}
+	}
+
+	r.declare(name)
+	if p.short != "" {
+		r.declareShort(name, p.short)
+	}
+
+	r.params = append(r.params, p)
+}
+
+// Load loads the values of the declared parameters and registered structs
+// like Load, with the same LoadOptions except WithDefaults. Every Load starts
+// from the zero values of the declared parameters, like Load into a new
+// struct.
+func (r *Registry) Load(opts ...LoadOption) error {
+	c := newLoadConfig(opts)
+	if c.defaults != nil {
+		return errors.New("WithDefaults is not supported by Registry")
+	}
+
+	for _, p := range r.params {
+		p.register(c.provider, p)
+
+		if sp, ok := c.provider.(ShortFlagProvider); ok && p.short != "" {
+			sp.ShortVar(p.name, p.short)
+		}
+
+		if op, ok := c.provider.(TagOptionProvider); ok {
+			for _, option := range p.options {
+				op.TagOption(p.name, option)
+			}
+		}
+	}
+
+	allocated := make([][]reflect.Value, len(r.structs))
+	for i, rs := range r.structs {
+		var err error
+		if allocated[i], err = rs.s.registerStruct(rs.v, c); err != nil {
+			return fmt.Errorf("%s: %w", rs.namespace, err)
+		}
+	}
+
+	if err := c.provider.Load(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	for _, rs := range r.structs {
+		if err := rs.s.resolve(rs.v, c); err != nil {
+			return fmt.Errorf("%s: %w", rs.namespace, err)
+		}
+	}
+
+	if missing := c.provider.Missing(); len(missing) > 0 {
+		return &MissingError{Names: missing}
+	}
+
+	for _, rs := range r.structs {
+		if err := rs.s.validate(rs.v); err != nil {
+			return err
+		}
+	}
+
+	if c.nilStructs {
+		for _, ptrs := range allocated {
+			resetNil(ptrs)
+		}
+	}
+
+	return nil
+}
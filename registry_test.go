@@ -0,0 +1,219 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := conf.NewRegistry()
+	port := conf.Int(reg, "port", 8080, conf.Short("p"))
+	host := conf.String(reg, "db.host", "", conf.Required())
+	debug := conf.Bool(reg, "debug", false)
+
+	vars := env{"DB_HOST": "db.example.com", "DEBUG": "true"}
+	err := reg.Load(conf.WithProviders(conf.NewEnvProvider(vars.Get), conf.NewFlagProvider([]string{"-p", "80"})))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *port != 80 || *host != "db.example.com" || !*debug {
+		t.Errorf("unexpected values: port=%d host=%s debug=%t", *port, *host, *debug)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		err := reg.Load(conf.WithProviders(conf.NewEnvProvider(env{}.Get)))
+		if err == nil || err.Error() != "missing configuration parameters: db.host" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("count option", func(t *testing.T) {
+		reg := conf.NewRegistry()
+		verbose := conf.Int(reg, "verbose", 0, conf.Short("v"), conf.Option("count"))
+		if err := reg.Load(conf.WithProviders(conf.NewFlagProvider([]string{"-vv"}))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if *verbose != 2 {
+			t.Errorf("unexpected verbose: %d", *verbose)
+		}
+	})
+
+	t.Run("invalid declarations", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			declare func(reg *conf.Registry)
+			want    string
+		}{
+			{
+				name:    "unknown option",
+				declare: func(reg *conf.Registry) { conf.String(reg, "token", "", conf.Option("fiel")) },
+				want:    `conf: unknown option "fiel" of token`,
+			},
+			{
+				name:    "count option on string",
+				declare: func(reg *conf.Registry) { conf.String(reg, "name", "", conf.Option("count")) },
+				want:    `conf: option "count" of name needs an int parameter, got string`,
+			},
+			{
+				name:    "list option on int",
+				declare: func(reg *conf.Registry) { conf.Int(reg, "port", 0, conf.Option("list")) },
+				want:    `conf: option "list" of port needs a string parameter, got int`,
+			},
+			{
+				name:    "long short name",
+				declare: func(reg *conf.Registry) { conf.Int(reg, "port", 0, conf.Short("po")) },
+				want:    `conf: short name "po" of port must be a single character`,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				defer func() {
+					if got := recover(); got != tt.want {
+						t.Errorf("unexpected panic: %v", got)
+					}
+				}()
+
+				tt.declare(conf.NewRegistry())
+			})
+		}
+	})
+
+	t.Run("declared twice", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+
+		conf.Int(reg, "port", 0)
+	})
+
+	t.Run("short declared twice", func(t *testing.T) {
+		defer func() {
+			if got := recover(); got != `conf: short name "p" of path is already used by port` {
+				t.Errorf("unexpected panic: %v", got)
+			}
+		}()
+
+		conf.String(reg, "path", "", conf.Short("p"))
+	})
+}
+
+type redisConfig struct {
+	Host string `conf:"host,required"`
+	DB   int    `conf:"db"`
+}
+
+type cacheConfig struct {
+	Size  int `conf:"size,default=128"`
+	Redis *struct {
+		Prefix string `conf:"prefix"`
+	} `conf:"redis"`
+}
+
+func TestRegistryRegister(t *testing.T) {
+	var redisCfg redisConfig
+	var cacheCfg cacheConfig
+
+	reg := conf.NewRegistry()
+	reg.Register("redis", &redisCfg)
+	reg.Register("cache", &cacheCfg)
+	debug := conf.Bool(reg, "debug", false)
+
+	vars := env{"REDIS_HOST": "redis.example.com", "REDIS_DB": "2", "CACHE_REDIS_PREFIX": "app:", "DEBUG": "true"}
+	if err := reg.Load(conf.WithProviders(conf.NewEnvProvider(vars.Get))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(redisConfig{Host: "redis.example.com", DB: 2}, redisCfg); diff != "" {
+		t.Errorf("unexpected redis config (-want +got):\n%s", diff)
+	}
+
+	if cacheCfg.Size != 128 || cacheCfg.Redis == nil || cacheCfg.Redis.Prefix != "app:" || !*debug {
+		t.Errorf("unexpected config: %+v, debug=%t", cacheCfg, *debug)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		var redisCfg redisConfig
+
+		reg := conf.NewRegistry()
+		reg.Register("redis", &redisCfg)
+
+		err := reg.Load(conf.WithProviders(conf.NewEnvProvider(env{}.Get)))
+		if err == nil || err.Error() != "missing configuration parameters: redis.host" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			register func(reg *conf.Registry)
+			want     string
+		}{
+			{
+				name:     "namespace",
+				register: func(reg *conf.Registry) { reg.Register("redis", &redisConfig{}) },
+				want:     "conf: parameter redis.host declared twice",
+			},
+			{
+				name:     "declared parameter",
+				register: func(reg *conf.Registry) { conf.String(reg, "cache.redis.prefix", "") },
+				want:     "conf: parameter cache.redis.prefix declared twice",
+			},
+			{
+				name:     "same environment variable",
+				register: func(reg *conf.Registry) { conf.String(reg, "redis_host", "") },
+				want:     "conf: parameter redis_host conflicts with redis.host, both are read from REDIS_HOST",
+			},
+			{
+				name: "namespace with dash",
+				register: func(reg *conf.Registry) {
+					reg.Register("cache-redis", &struct {
+						Prefix string `conf:"prefix"`
+					}{})
+				},
+				want: "conf: parameter cache-redis.prefix conflicts with cache.redis.prefix, both are read from CACHE_REDIS_PREFIX",
+			},
+			{
+				name:     "not a struct",
+				register: func(reg *conf.Registry) { reg.Register("other", redisConfig{}) },
+				want:     "conf: failed to register other: expected pointer to struct, got struct",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				defer func() {
+					if got := recover(); got != tt.want {
+						t.Errorf("unexpected panic: %v", got)
+					}
+				}()
+
+				tt.register(reg)
+			})
+		}
+	})
+}
+
+// registeredRedis is registered with the DefaultRegistry like by a library,
+// once for all test runs.
+var registeredRedis redisConfig
+
+func init() {
+	conf.Register("registered-redis", &registeredRedis)
+}
+
+func TestLoadRegistered(t *testing.T) {
+	vars := env{"REGISTERED_REDIS_HOST": "redis.example.com"}
+	if err := conf.LoadRegistered(conf.WithProviders(conf.NewEnvProvider(vars.Get))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if registeredRedis.Host != "redis.example.com" {
+		t.Errorf("expected value %s, got %s", "redis.example.com", registeredRedis.Host)
+	}
+}
@@ -0,0 +1,473 @@
+package conf
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/solhall/conf/internal/structtag"
+)
+
+// Schema is the compiled form of the configuration struct T. It loads like
+// Load, without walking the struct and parsing its tags on every call.
+type Schema[T any] struct {
+	s *schema
+}
+
+// Compile walks the struct T once and returns its Schema, or an error if a tag
+// is invalid.
+func Compile[T any]() (*Schema[T], error) {
+	t, err := structType((*T)(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := compileStruct(t, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema[T]{s: s}, nil
+}
+
+// Load loads configuration values into cfg like Load.
+func (s *Schema[T]) Load(cfg *T, opts ...LoadOption) error {
+	return s.s.load(reflect.ValueOf(cfg).Elem(), newLoadConfig(opts))
+}
+
+// schema describes how the fields of a struct type are registered with a
+// provider.
+type schema struct {
+	typ reflect.Type
+	// pointers are the pointers to structs to allocate before registering
+	// the fields, outer ones first.
+	pointers []schemaPointer
+	fields   []schemaField
+}
+
+type schemaPointer struct {
+	name  string
+	index []int
+}
+
+// schemaField is a parameter or positional argument.
+type schemaField struct {
+	name     string
+	index    []int
+	kind     reflect.Kind
+	required bool
+	fallback string
+	// fallbackInt and fallbackBool are fallback parsed for int and bool
+	// fields.
+	fallbackInt  int
+	fallbackBool bool
+	short        string
+	options      []string
+	validation   validation
+	usage        string
+	// static is whether the field has the "static" option.
+	static bool
+	// arg is whether the field is a positional argument at argIndex.
+	arg      bool
+	argIndex int
+}
+
+// compileConfig returns the schema of the struct cfg points to.
+func compileConfig(cfg any) (*schema, error) {
+	t, err := structType(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return compileStruct(t, "")
+}
+
+// options returns the fields that are not positional arguments, which are
+// read from the environment and flags.
+func (s *schema) options() []schemaField {
+	var options []schemaField
+	for _, f := range s.fields {
+		if !f.arg {
+			options = append(options, f)
+		}
+	}
+
+	return options
+}
+
+// compileStruct walks the struct type t and returns its schema. prefix is
+// prepended to the names of all parameters.
+func compileStruct(t reflect.Type, prefix string) (*schema, error) {
+	s := &schema{typ: t}
+	if err := s.addStruct(t, prefix, nil, nil, true); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// addStruct adds the fields of the struct t at index, which is nested in the
+// pointers to structs parents. settable is whether the exported fields of t
+// can be set through reflection.
+func (s *schema) addStruct(t reflect.Type, prefix string, index []int, parents []reflect.Type, settable bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(slices.Clone(index), i)
+		if err := s.addField(prefix, field, fieldIndex, parents, settable); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *schema) addField(prefix string, field reflect.StructField, index []int, parents []reflect.Type, settable bool) error {
+	// if field is embedded struct, recursively load it
+	if isNestedStruct(field) {
+		if _, err := structtag.Parse(field.Tag.Get(tagName)); err != nil {
+			return fmt.Errorf("field %s: invalid conf tag: %w", field.Name, err)
+		}
+
+		// The exported fields of embedded structs can be set even if the
+		// struct type is unexported.
+		settable = settable && (field.IsExported() || field.Anonymous)
+
+		structType := field.Type
+		if structType.Kind() == reflect.Ptr {
+			if slices.Contains(parents, structType) {
+				return fmt.Errorf("field %s: recursive type %s", field.Name, field.Type)
+			}
+			parents = append(slices.Clone(parents), structType)
+			structType = structType.Elem()
+
+			s.pointers = append(s.pointers, schemaPointer{name: field.Name, index: index})
+		}
+
+		return s.addStruct(structType, structPrefix(prefix, field), index, parents, settable)
+	}
+
+	arg, hasArg := field.Tag.Lookup(argTagName)
+	if (hasArg || field.Tag.Get(tagName) != "") && !(settable && field.IsExported()) {
+		return fmt.Errorf("field %s: cannot load unexported field, export it or remove its tags", field.Name)
+	}
+
+	if hasArg {
+		return s.addArg(prefix, field, index, arg)
+	}
+
+	// e.g. "field1,default=my value,required"
+	tagVal := field.Tag.Get(tagName)
+	if tagVal == "" {
+		return nil
+	}
+
+	tag, err := structtag.Parse(tagVal)
+	if err != nil {
+		return fmt.Errorf("field %s: invalid conf tag: %w", field.Name, err)
+	}
+
+	kind, err := fieldKind(field)
+	if err != nil {
+		return err
+	}
+
+	f := schemaField{
+		name:     prefix + tag.Name,
+		index:    index,
+		kind:     kind,
+		required: tag.Required,
+		fallback: tag.Fallback,
+		options:  tag.Options,
+		usage:    field.Tag.Get(usageTagName),
+		static:   slices.Contains(tag.Options, "static"),
+	}
+
+	switch f.kind {
+	case reflect.Int:
+		if f.fallback != "" {
+			if f.fallbackInt, err = strconv.Atoi(f.fallback); err != nil {
+				return fmt.Errorf("failed to parse fallback value %q as int: %w", f.fallback, err)
+			}
+		}
+	case reflect.String:
+	case reflect.Bool:
+		if f.fallback != "" {
+			if f.fallbackBool, err = strconv.ParseBool(f.fallback); err != nil {
+				return fmt.Errorf("failed to parse fallback value %q as bool: %w", f.fallback, err)
+			}
+		}
+	default:
+		return nil
+	}
+
+	if short := field.Tag.Get(shortTagName); short != "" {
+		if len(short) != 1 {
+			return fmt.Errorf("short name %q of %s must be a single character", short, f.name)
+		}
+		for _, other := range s.fields {
+			if other.short == short {
+				return fmt.Errorf("short name %q of %s is already used by %s", short, f.name, other.name)
+			}
+		}
+		f.short = short
+	}
+
+	if slices.Contains(f.options, "count") && f.kind != reflect.Int {
+		return fmt.Errorf("field %s: option \"count\" needs an int field, got %s", field.Name, f.kind)
+	}
+	if slices.Contains(f.options, "list") && f.kind != reflect.String {
+		return fmt.Errorf("field %s: option \"list\" needs a string field, got %s", field.Name, f.kind)
+	}
+
+	if f.validation, err = parseValidation(field, f.kind); err != nil {
+		return err
+	}
+
+	if tag := f.validation.tag(); tag != "" && !f.required && f.fallback == "" {
+		return fmt.Errorf("field %s: %s tag needs a default or the required option", field.Name, tag)
+	}
+
+	s.fields = append(s.fields, f)
+
+	return nil
+}
+
+// fieldKind returns the kind of a field if its type is exactly string, int or
+// bool. Named types like `type Level string` are rejected, since providers
+// set a *string, *int or *bool, and other types are not loaded, like with
+// cmd/confgen.
+func fieldKind(field reflect.StructField) (reflect.Kind, error) {
+	switch field.Type {
+	case reflect.TypeOf(""):
+		return reflect.String, nil
+	case reflect.TypeOf(0):
+		return reflect.Int, nil
+	case reflect.TypeOf(false):
+		return reflect.Bool, nil
+	}
+
+	if field.Type.PkgPath() != "" {
+		return reflect.Invalid, fmt.Errorf("field %s: unsupported type %s, use string, int or bool", field.Name, field.Type)
+	}
+
+	return reflect.Invalid, nil
+}
+
+// addArg adds a field tagged with `arg` as a positional argument. Its name and
+// options are taken from the `conf` tag, if any.
+func (s *schema) addArg(prefix string, field reflect.StructField, index []int, arg string) error {
+	tag, err := structtag.Parse(field.Tag.Get(tagName))
+	if err != nil {
+		return fmt.Errorf("field %s: invalid conf tag: %w", field.Name, err)
+	}
+
+	name := tag.Name
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	argIndex, err := parseArgIndex(arg)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", field.Name, err)
+	}
+
+	if argIndex == ArgRest {
+		if field.Type != reflect.TypeOf([]string(nil)) {
+			return fmt.Errorf("field %s: rest arguments must be a []string, got %s", field.Name, field.Type)
+		}
+	} else {
+		kind, err := fieldKind(field)
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Invalid {
+			return fmt.Errorf("field %s: positional argument has unsupported type %s", field.Name, field.Type)
+		}
+	}
+
+	s.fields = append(s.fields, schemaField{
+		name:     prefix + name,
+		index:    index,
+		kind:     field.Type.Kind(),
+		required: tag.Required,
+		fallback: tag.Fallback,
+		usage:    field.Tag.Get(usageTagName),
+		arg:      true,
+		argIndex: argIndex,
+	})
+
+	return nil
+}
+
+// load loads the configuration into v, a struct of the schema's type.
+func (s *schema) load(v reflect.Value, c *loadConfig) error {
+	allocated, err := s.registerStruct(v, c)
+	if err != nil {
+		return err
+	}
+
+	if err := c.provider.Load(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := s.resolve(v, c); err != nil {
+		return err
+	}
+
+	if missing := c.provider.Missing(); len(missing) > 0 {
+		return &MissingError{Names: missing}
+	}
+
+	if err := s.validate(v); err != nil {
+		return err
+	}
+
+	if c.nilStructs {
+		resetNil(allocated)
+	}
+
+	return nil
+}
+
+// registerStruct registers the fields of v with the provider, after
+// allocating the nil pointers to structs, which it returns.
+func (s *schema) registerStruct(v reflect.Value, c *loadConfig) ([]reflect.Value, error) {
+	if c.defaults != nil {
+		if err := s.copyDefaults(v, c.defaults); err != nil {
+			return nil, err
+		}
+	}
+
+	var allocated []reflect.Value
+	for _, p := range s.pointers {
+		ptr := v.FieldByIndex(p.index)
+		if !ptr.IsNil() {
+			continue
+		}
+		if !ptr.CanSet() {
+			return nil, fmt.Errorf("field %s: cannot allocate unexported pointer to struct", p.name)
+		}
+
+		ptr.Set(reflect.New(ptr.Type().Elem()))
+		allocated = append(allocated, ptr)
+	}
+
+	for _, f := range s.fields {
+		s.register(c, f, v.FieldByIndex(f.index))
+	}
+
+	return allocated, nil
+}
+
+// copyDefaults sets v to a copy of defaults, a struct of the schema's type or a
+// pointer to one. The structs pointed to are copied as well, so that loading
+// into v does not modify defaults.
+func (s *schema) copyDefaults(v reflect.Value, defaults any) error {
+	dv := reflect.ValueOf(defaults)
+	if dv.Kind() == reflect.Ptr {
+		if dv.IsNil() {
+			return fmt.Errorf("expected defaults of type %s, got nil %T", s.typ, defaults)
+		}
+		dv = dv.Elem()
+	}
+	if dv.Type() != s.typ {
+		return fmt.Errorf("expected defaults of type %s, got %T", s.typ, defaults)
+	}
+	v.Set(dv)
+
+	// Outer pointers come first, so the inner ones are copied from within
+	// the copies of the outer structs.
+	for _, p := range s.pointers {
+		ptr, err := v.FieldByIndexErr(p.index)
+		if err != nil || ptr.IsNil() {
+			continue
+		}
+		if !ptr.CanSet() {
+			return fmt.Errorf("field %s: cannot copy unexported pointer to struct from defaults", p.name)
+		}
+
+		copied := reflect.New(ptr.Type().Elem())
+		copied.Elem().Set(ptr.Elem())
+		ptr.Set(copied)
+	}
+
+	return nil
+}
+
+// resolve decrypts the values of v and resolves its secrets, after the
+// provider loaded them.
+func (s *schema) resolve(v reflect.Value, c *loadConfig) error {
+	if c.decrypt != nil {
+		if err := c.decrypt(v, s.fields); err != nil {
+			return err
+		}
+	}
+
+	if c.secrets != nil {
+		if err := c.secrets.resolve(v, s.fields); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetNil sets the pointers to structs in allocated back to nil if their
+// structs are still zero.
+func resetNil(allocated []reflect.Value) {
+	// Inner structs are allocated after outer ones, and must be reset first
+	// for the outer ones to be zero.
+	for i := len(allocated) - 1; i >= 0; i-- {
+		if ptr := allocated[i]; ptr.Elem().IsZero() {
+			ptr.Set(reflect.Zero(ptr.Type()))
+		}
+	}
+}
+
+// register registers the field f, whose value is value, with the provider.
+func (s *schema) register(c *loadConfig, f schemaField, value reflect.Value) {
+	if f.arg {
+		if p, ok := c.provider.(ArgProvider); ok {
+			p.ArgVar(value.Addr().Interface(), f.name, f.argIndex, f.fallback, f.required)
+		}
+		return
+	}
+
+	// With WithDefaults, the field already holds its default value.
+	fromDefaults := c.defaults != nil && !value.IsZero()
+
+	switch f.kind {
+	case reflect.Int:
+		fallback := f.fallbackInt
+		if fromDefaults {
+			fallback = int(value.Int())
+		}
+		c.provider.IntVar(value.Addr().Interface().(*int), f.name, fallback, f.required)
+	case reflect.String:
+		fallback := f.fallback
+		if fromDefaults {
+			fallback = value.String()
+		}
+		c.provider.StringVar(value.Addr().Interface().(*string), f.name, fallback, f.required)
+	case reflect.Bool:
+		fallback := f.fallbackBool
+		if fromDefaults {
+			fallback = value.Bool()
+		}
+		c.provider.BoolVar(value.Addr().Interface().(*bool), f.name, fallback, f.required)
+	}
+
+	if f.short != "" {
+		if p, ok := c.provider.(ShortFlagProvider); ok {
+			p.ShortVar(f.name, f.short)
+		}
+	}
+
+	if p, ok := c.provider.(TagOptionProvider); ok {
+		for _, option := range f.options {
+			p.TagOption(f.name, option)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package conf_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type tenantConfig struct {
+	Name    string `conf:"name,required"`
+	Plan    string `conf:"plan,default=free"`
+	Seats   int    `conf:"seats,default=5"`
+	Enabled bool   `conf:"enabled,default=true"`
+	DB      struct {
+		Host string `conf:"host,required"`
+		Port int    `conf:"port,default=5432"`
+	} `conf:"db"`
+	Limits *struct {
+		Requests int `conf:"requests"`
+	} `conf:"limits"`
+}
+
+var tenantValues = map[string]string{
+	"name":            "acme",
+	"seats":           "50",
+	"db.host":         "db.example.com",
+	"limits.requests": "1000",
+}
+
+func TestCompile(t *testing.T) {
+	schema, err := conf.Compile[tenantConfig]()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want tenantConfig
+	if err := conf.Load(&want, conf.WithProviders(conf.NewMapProvider(tenantValues))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var cfg tenantConfig
+		if err := schema.Load(&cfg, conf.WithProviders(conf.NewMapProvider(tenantValues))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("unexpected config (-want +got):\n%s", diff)
+		}
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		var cfg tenantConfig
+		err := schema.Load(&cfg, conf.WithProviders(conf.NewMapProvider(map[string]string{"name": "acme"})))
+		if err == nil || err.Error() != "missing configuration parameters: db.host" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		type invalid struct {
+			Port int `conf:"port,default=abc"`
+		}
+
+		_, err := conf.Compile[invalid]()
+		if err == nil || !strings.HasPrefix(err.Error(), `failed to parse fallback value "abc" as int`) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("named type", func(t *testing.T) {
+		type level string
+		type named struct {
+			Level level `conf:"level"`
+		}
+
+		_, err := conf.Compile[named]()
+		if err == nil || err.Error() != "field Level: unsupported type conf_test.level, use string, int or bool" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		if _, err := conf.Compile[int](); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func BenchmarkLoad(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var cfg tenantConfig
+		if err := conf.Load(&cfg, conf.WithProviders(conf.NewMapProvider(tenantValues))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSchemaLoad(b *testing.B) {
+	schema, err := conf.Compile[tenantConfig]()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var cfg tenantConfig
+		if err := schema.Load(&cfg, conf.WithProviders(conf.NewMapProvider(tenantValues))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSecretTimeout = 10 * time.Second
+	redacted             = "[REDACTED]"
+)
+
+// SecretResolver resolves references to secrets, e.g. vault://kv/app#password,
+// into the secret values.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+// Secrets is a registry of SecretResolvers by URI scheme. With WithSecrets,
+// every string parameter whose value is a URI with a registered scheme is
+// replaced with the secret it refers to, whichever provider it came from.
+//
+// Secrets remembers the resolved values, so that they can be redacted from
+// logs and error messages with Redact.
+type Secrets struct {
+	resolvers map[string]SecretResolver
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	values []string
+}
+
+// NewSecrets creates an empty registry of SecretResolvers.
+func NewSecrets() *Secrets {
+	return &Secrets{
+		resolvers: make(map[string]SecretResolver),
+		timeout:   defaultSecretTimeout,
+	}
+}
+
+// Register sets the resolver for references with the given URI scheme, e.g.
+// "vault".
+func (s *Secrets) Register(scheme string, r SecretResolver) *Secrets {
+	s.resolvers[scheme] = r
+	return s
+}
+
+// WithTimeout sets how long resolving all secrets of a Load may take. The
+// default is ten seconds. Load fails after the timeout even if a resolver
+// ignores the context.
+func (s *Secrets) WithTimeout(d time.Duration) *Secrets {
+	s.timeout = d
+	return s
+}
+
+// Redact replaces every secret resolved so far in text with "[REDACTED]".
+func (s *Secrets) Redact(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, value := range s.values {
+		text = strings.ReplaceAll(text, value, redacted)
+	}
+
+	return text
+}
+
+// WithSecrets returns a LoadOption that resolves secret references in the
+// loaded values with the resolvers registered in s.
+func WithSecrets(s *Secrets) LoadOption {
+	return func(c *loadConfig) {
+		c.secrets = s
+	}
+}
+
+// resolve replaces the secret references in the string parameters of the
+// struct v, whose fields are described by fields. All references are resolved
+// concurrently.
+func (s *Secrets) resolve(v reflect.Value, fields []schemaField) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	type result struct {
+		index int
+		value string
+		err   error
+	}
+
+	var refFields []schemaField
+	var refs []*url.URL
+	for _, f := range fields {
+		if f.arg || f.kind != reflect.String {
+			continue
+		}
+
+		ref, err := url.Parse(v.FieldByIndex(f.index).String())
+		if err != nil || s.resolvers[ref.Scheme] == nil {
+			continue
+		}
+
+		refFields = append(refFields, f)
+		refs = append(refs, ref)
+	}
+
+	// The channel is buffered, so that resolvers finishing after the
+	// timeout do not block.
+	done := make(chan result, len(refs))
+	for i, ref := range refs {
+		go func(i int, ref *url.URL) {
+			value, err := s.resolvers[ref.Scheme].Resolve(ctx, ref)
+			done <- result{index: i, value: value, err: err}
+		}(i, ref)
+	}
+
+	// Resolvers that ignore ctx are not waited for after the timeout.
+	results := make([]result, len(refs))
+	resolved := make([]bool, len(refs))
+wait:
+	for range refs {
+		select {
+		case res := <-done:
+			results[res.index] = res
+			resolved[res.index] = true
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	var errs []error
+	for i, res := range results {
+		if !resolved[i] {
+			res.err = ctx.Err()
+		}
+		if res.err != nil {
+			errs = append(errs, fmt.Errorf("failed to resolve secret for %s: %w", refFields[i].name, res.err))
+			continue
+		}
+
+		v.FieldByIndex(refFields[i].index).SetString(res.value)
+
+		if res.value != "" {
+			s.mu.Lock()
+			if !slices.Contains(s.values, res.value) {
+				s.values = append(s.values, res.value)
+			}
+			s.mu.Unlock()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// FileResolver resolves file:///path references to the content of the file,
+// with trailing newlines trimmed.
+type FileResolver struct{}
+
+func (FileResolver) Resolve(_ context.Context, ref *url.URL) (string, error) {
+	if ref.Host != "" && ref.Host != "localhost" {
+		return "", fmt.Errorf("unsupported host %q in file reference", ref.Host)
+	}
+
+	content, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// EnvResolver resolves env://NAME references to the value of the environment
+// variable NAME.
+type EnvResolver struct {
+	Getenv func(string) string
+}
+
+func (r EnvResolver) Resolve(_ context.Context, ref *url.URL) (string, error) {
+	name := ref.Host
+	if name == "" {
+		name = ref.Opaque
+	}
+
+	value := r.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
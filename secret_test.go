@@ -0,0 +1,133 @@
+package conf_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeVault is an in-memory SecretResolver.
+type fakeVault map[string]string
+
+func (v fakeVault) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	secret, ok := v[ref.Host+ref.Path+"#"+ref.Fragment]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+
+	return secret, nil
+}
+
+// slowResolver blocks until the context is done.
+type slowResolver struct{}
+
+func (slowResolver) Resolve(ctx context.Context, _ *url.URL) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+// stuckResolver ignores the context and blocks until release is closed.
+type stuckResolver struct {
+	release chan struct{}
+}
+
+func (r stuckResolver) Resolve(context.Context, *url.URL) (string, error) {
+	<-r.release
+	return "", nil
+}
+
+func TestSecrets(t *testing.T) {
+	type mystruct struct {
+		DBPassword string `conf:"db_password"`
+		APIKey     string `conf:"api_key"`
+		Token      string `conf:"token"`
+		Homepage   string `conf:"homepage"`
+	}
+
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	environment := env{
+		"DB_PASSWORD":  "vault://kv/app#password",
+		"API_KEY":      "env://REAL_API_KEY",
+		"TOKEN":        "file://" + filepath.ToSlash(secretFile),
+		"HOMEPAGE":     "https://example.com",
+		"REAL_API_KEY": "k3y",
+	}
+
+	secrets := conf.NewSecrets().
+		Register("vault", fakeVault{"kv/app#password": "hunter2"}).
+		Register("env", conf.EnvResolver{Getenv: environment.Get}).
+		Register("file", conf.FileResolver{})
+
+	var cfg mystruct
+	if err := conf.Load(&cfg,
+		conf.WithProviders(conf.NewEnvProvider(environment.Get)),
+		conf.WithSecrets(secrets),
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := mystruct{
+		DBPassword: "hunter2",
+		APIKey:     "k3y",
+		Token:      "file-token",
+		Homepage:   "https://example.com",
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	if got := secrets.Redact("connecting with hunter2 and k3y"); got != "connecting with [REDACTED] and [REDACTED]" {
+		t.Errorf("unexpected redacted text: %s", got)
+	}
+
+	t.Run("unresolvable", func(t *testing.T) {
+		var cfg mystruct
+		err := conf.Load(&cfg,
+			conf.WithProviders(conf.NewEnvProvider(env{"DB_PASSWORD": "vault://kv/other#password"}.Get)),
+			conf.WithSecrets(secrets),
+		)
+		if err == nil || err.Error() != "failed to resolve secret for db_password: secret not found" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		secrets := conf.NewSecrets().Register("slow", slowResolver{}).WithTimeout(10 * time.Millisecond)
+
+		var cfg mystruct
+		err := conf.Load(&cfg,
+			conf.WithProviders(conf.NewEnvProvider(env{"TOKEN": "slow://token"}.Get)),
+			conf.WithSecrets(secrets),
+		)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("resolver ignoring timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		secrets := conf.NewSecrets().Register("stuck", stuckResolver{release: release}).WithTimeout(10 * time.Millisecond)
+
+		var cfg mystruct
+		err := conf.Load(&cfg,
+			conf.WithProviders(conf.NewEnvProvider(env{"TOKEN": "stuck://token"}.Get)),
+			conf.WithSecrets(secrets),
+		)
+		if err == nil || err.Error() != "failed to resolve secret for token: context deadline exceeded" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds an immutable snapshot of a configuration, which is safe to use
+// from multiple goroutines while it is being reloaded.
+type Store[T any] struct {
+	current atomic.Pointer[T]
+	schema  *schema
+	opts    []LoadOption
+
+	// mu serializes reloads, since providers are not safe for concurrent
+	// use.
+	mu       sync.Mutex
+	onChange func(changes []Change)
+}
+
+// Change is a configuration parameter whose value changed on reload. Old and
+// New may be secrets, resolved with WithSecrets or decrypted, so they are left
+// out of String and StaticChangeError, and should not be logged.
+type Change struct {
+	Name string
+	Old  any
+	New  any
+	// Static reports whether the parameter is tagged as static, e.g.
+	// `conf:"listen-addr,static"`, and must not change after startup.
+	Static bool
+}
+
+func (c Change) String() string {
+	return c.Name + " changed"
+}
+
+// WithOnChange returns a LoadOption that sets a function that is called with
+// the changed parameters after Watch or Store.Reload swapped in a new
+// configuration.
+func WithOnChange(f func(changes []Change)) LoadOption {
+	return func(c *loadConfig) {
+		c.onChange = f
+	}
+}
+
+// StaticChangeError is returned by Store.Reload when parameters tagged as
+// static changed.
+type StaticChangeError struct {
+	Changes []Change
+}
+
+func (e *StaticChangeError) Error() string {
+	names := make([]string, len(e.Changes))
+	for i, change := range e.Changes {
+		names[i] = change.Name
+	}
+
+	return "static configuration parameters cannot change: " + strings.Join(names, ", ")
+}
+
+// NewStore loads the configuration into a new T with the given options, like
+// Load, and returns a Store holding it.
+func NewStore[T any](opts ...LoadOption) (*Store[T], error) {
+	return newStore(new(T), opts)
+}
+
+func newStore[T any](cfg *T, opts []LoadOption) (*Store[T], error) {
+	schema, err := compileConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newLoadConfig(opts)
+	if err := schema.load(reflect.ValueOf(cfg).Elem(), c); err != nil {
+		return nil, err
+	}
+
+	s := &Store[T]{schema: schema, opts: opts, onChange: c.onChange}
+	s.current.Store(cfg)
+
+	return s, nil
+}
+
+// Get returns the current configuration. It must not be modified, since it
+// is shared with all other callers.
+func (s *Store[T]) Get() *T {
+	return s.current.Load()
+}
+
+// Reload loads the configuration again into a new T, which is published only
+// if it loaded without errors. Callers of Get either see the previous or the
+// new configuration, never a partially loaded one. If the options include
+// WithOnChange, it is called with the changed parameters.
+//
+// If a parameter tagged as static, e.g. `conf:"listen-addr,static"`, changed,
+// the new configuration is rejected with a *StaticChangeError.
+func (s *Store[T]) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := new(T)
+	if err := s.schema.load(reflect.ValueOf(next).Elem(), newLoadConfig(s.opts)); err != nil {
+		return err
+	}
+
+	prev := s.current.Load()
+	changes := diffFields(s.schema.fields, reflect.ValueOf(prev).Elem(), reflect.ValueOf(next).Elem())
+
+	var static []Change
+	for _, change := range changes {
+		if change.Static {
+			static = append(static, change)
+		}
+	}
+	if len(static) > 0 {
+		return &StaticChangeError{Changes: static}
+	}
+
+	s.current.Store(next)
+
+	if len(changes) > 0 && s.onChange != nil {
+		s.onChange(changes)
+	}
+
+	return nil
+}
+
+// diffFields returns the configuration parameters whose values differ between
+// the structs old and new, whose fields are described by fields.
+func diffFields(fields []schemaField, old, new reflect.Value) []Change {
+	var changes []Change
+	for _, f := range fields {
+		o, n := fieldValue(old, f.index), fieldValue(new, f.index)
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, Change{Name: f.name, Old: o, New: n, Static: f.static})
+		}
+	}
+
+	return changes
+}
+
+// fieldValue returns the value of the nested field of v at index, or nil if
+// it is in a nil pointer to a struct or unexported.
+func fieldValue(v reflect.Value, index []int) any {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil || !field.CanInterface() {
+		return nil
+	}
+
+	return field.Interface()
+}
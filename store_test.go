@@ -0,0 +1,190 @@
+package conf_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/solhall/conf"
+)
+
+func TestStore(t *testing.T) {
+	type mystruct struct {
+		Host string `conf:"host,required"`
+		Port int    `conf:"port"`
+	}
+
+	// generation is both the port and part of the host, so that a
+	// partially loaded config can be detected.
+	var generation atomic.Int64
+	getenv := func(key string) string {
+		n := generation.Load()
+		switch key {
+		case "HOST":
+			return fmt.Sprintf("host-%d", n)
+		case "PORT":
+			return fmt.Sprint(n)
+		}
+		return ""
+	}
+
+	store, err := conf.NewStore[mystruct](conf.WithProviders(conf.NewEnvProvider(getenv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := store.Get(); got.Host != "host-0" || got.Port != 0 {
+		t.Fatalf("unexpected initial config: %+v", got)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				cfg := store.Get()
+				if want := fmt.Sprintf("host-%d", cfg.Port); cfg.Host != want {
+					t.Errorf("inconsistent snapshot: %+v", cfg)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= 100; i++ {
+		generation.Store(int64(i))
+		if err := store.Reload(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if got := store.Get(); got.Host != "host-100" || got.Port != 100 {
+		t.Fatalf("unexpected final config: %+v", got)
+	}
+}
+
+func TestStoreReloadError(t *testing.T) {
+	type mystruct struct {
+		Host string `conf:"host,required"`
+	}
+
+	env := env{"HOST": "example.com"}
+	store, err := conf.NewStore[mystruct](conf.WithProviders(conf.NewEnvProvider(env.Get)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delete(env, "HOST")
+	if err := store.Reload(); err == nil || err.Error() != "missing configuration parameters: host" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := store.Get(); got.Host != "example.com" {
+		t.Fatalf("expected value %s, got %s", "example.com", got.Host)
+	}
+}
+
+func TestStoreStatic(t *testing.T) {
+	type mystruct struct {
+		ListenAddr string `conf:"listen-addr,static"`
+		Upstreams  string `conf:"upstreams"`
+	}
+
+	env := env{"LISTEN_ADDR": ":80", "UPSTREAMS": "a"}
+	store, err := conf.NewStore[mystruct](conf.WithProviders(conf.NewEnvProvider(env.Get)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env["UPSTREAMS"] = "b"
+	if err := store.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env["LISTEN_ADDR"] = ":81"
+	env["UPSTREAMS"] = "c"
+	err = store.Reload()
+
+	var staticErr *conf.StaticChangeError
+	if !errors.As(err, &staticErr) {
+		t.Fatalf("expected *conf.StaticChangeError, got %v", err)
+	}
+
+	if err.Error() != "static configuration parameters cannot change: listen-addr" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got := store.Get(); got.ListenAddr != ":80" || got.Upstreams != "b" {
+		t.Errorf("unexpected config after rejected reload: %+v", got)
+	}
+
+	t.Run("secret", func(t *testing.T) {
+		var changes []conf.Change
+
+		vars := map[string]string{"TOKEN": "env://REAL_TOKEN", "REAL_TOKEN": "hunter2"}
+		getenv := func(key string) string { return vars[key] }
+		secrets := conf.NewSecrets().Register("env", conf.EnvResolver{Getenv: getenv})
+		store, err := conf.NewStore[struct {
+			Token string `conf:"token"`
+		}](conf.WithProviders(conf.NewEnvProvider(getenv)), conf.WithSecrets(secrets), conf.WithOnChange(func(c []conf.Change) {
+			changes = c
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		vars["REAL_TOKEN"] = "hunter3"
+		if err := store.Reload(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(changes) != 1 || changes[0].String() != "token changed" {
+			t.Errorf("unexpected changes: %v", changes)
+		}
+	})
+}
+
+func TestStoreWithDefaults(t *testing.T) {
+	type db struct {
+		Host string `conf:"host"`
+	}
+	type mystruct struct {
+		DB *db `conf:"db"`
+	}
+
+	host := "old"
+	getenv := func(key string) string {
+		if key == "DB_HOST" {
+			return host
+		}
+		return ""
+	}
+
+	defaults := mystruct{DB: &db{Host: "localhost"}}
+	store, err := conf.NewStore[mystruct](conf.WithProviders(conf.NewEnvProvider(getenv)), conf.WithDefaults(&defaults))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := store.Get()
+
+	host = "new"
+	if err := store.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first.DB.Host != "old" || store.Get().DB.Host != "new" || defaults.DB.Host != "localhost" {
+		t.Errorf("snapshots share structs: first %s, current %s, defaults %s", first.DB.Host, store.Get().DB.Host, defaults.DB.Host)
+	}
+}
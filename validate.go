@@ -0,0 +1,144 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// enumTagName lists the allowed values of a string parameter, e.g.
+	// `enum:"debug,info,warn"`.
+	enumTagName = "enum"
+	// minTagName and maxTagName bound the value of an int parameter, e.g.
+	// `min:"1" max:"65535"`.
+	minTagName = "min"
+	maxTagName = "max"
+)
+
+// validation holds the constraints on the value of a parameter. Parameters
+// with constraints need a default or the required option, since the zero value
+// of an unset parameter is checked like any other value.
+type validation struct {
+	enum     []string
+	min, max *int
+}
+
+// parseValidation parses the validation tags of a field of the given kind.
+func parseValidation(field reflect.StructField, kind reflect.Kind) (validation, error) {
+	var v validation
+
+	if enum, ok := field.Tag.Lookup(enumTagName); ok {
+		if kind != reflect.String {
+			return validation{}, fmt.Errorf("field %s: enum tag on %s field, only string fields are supported", field.Name, kind)
+		}
+		v.enum = strings.Split(enum, ",")
+	}
+
+	for _, bound := range []struct {
+		tag string
+		to  **int
+	}{{minTagName, &v.min}, {maxTagName, &v.max}} {
+		raw, ok := field.Tag.Lookup(bound.tag)
+		if !ok {
+			continue
+		}
+		if kind != reflect.Int {
+			return validation{}, fmt.Errorf("field %s: %s tag on %s field, only int fields are supported", field.Name, bound.tag, kind)
+		}
+
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return validation{}, fmt.Errorf("field %s: invalid %s tag %q: %w", field.Name, bound.tag, raw, err)
+		}
+		*bound.to = &n
+	}
+
+	return v, nil
+}
+
+// tag returns the name of the first validation tag of the field, or "" if it
+// has none.
+func (v validation) tag() string {
+	switch {
+	case v.enum != nil:
+		return enumTagName
+	case v.min != nil:
+		return minTagName
+	case v.max != nil:
+		return maxTagName
+	default:
+		return ""
+	}
+}
+
+// check returns an error if value, the value of the parameter name, violates
+// the constraints.
+func (v validation) check(name string, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.String:
+		if len(v.enum) > 0 {
+			return CheckEnum(name, value.String(), v.enum...)
+		}
+	case reflect.Int:
+		var errs []error
+		if v.min != nil {
+			errs = append(errs, CheckMin(name, int(value.Int()), *v.min))
+		}
+		if v.max != nil {
+			errs = append(errs, CheckMax(name, int(value.Int()), *v.max))
+		}
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// CheckEnum returns an error if value, the value of the parameter name, is not
+// one of enum, like the `enum` tag. It is used by the loaders generated by
+// cmd/confgen.
+func CheckEnum(name, value string, enum ...string) error {
+	if slices.Contains(enum, value) {
+		return nil
+	}
+
+	return fmt.Errorf("invalid value %q for %s: must be one of %s", value, name, strings.Join(enum, ", "))
+}
+
+// CheckMin returns an error if value, the value of the parameter name, is less
+// than min, like the `min` tag. It is used by the loaders generated by
+// cmd/confgen.
+func CheckMin(name string, value, min int) error {
+	if value >= min {
+		return nil
+	}
+
+	return fmt.Errorf("invalid value %d for %s: must be at least %d", value, name, min)
+}
+
+// CheckMax returns an error if value, the value of the parameter name, is
+// greater than max, like the `max` tag. It is used by the loaders generated by
+// cmd/confgen.
+func CheckMax(name string, value, max int) error {
+	if value <= max {
+		return nil
+	}
+
+	return fmt.Errorf("invalid value %d for %s: must be at most %d", value, name, max)
+}
+
+// validate checks the values of the fields of v, a struct of the schema's
+// type, against their validation tags.
+func (s *schema) validate(v reflect.Value) error {
+	var errs []error
+	for _, f := range s.fields {
+		if err := f.validation.check(f.name, v.FieldByIndex(f.index)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
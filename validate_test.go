@@ -0,0 +1,82 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/solhall/conf"
+)
+
+func TestLoadValidation(t *testing.T) {
+	type config struct {
+		Level string `conf:"level,default=info" enum:"debug,info,warn"`
+		Port  int    `conf:"port,default=8080" min:"1" max:"65535"`
+		Name  string `conf:"name,default=a" enum:"a,b"`
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		var cfg config
+		if err := conf.Load(&cfg, conf.WithProviders(conf.NewEnvProvider(env{"LEVEL": "debug", "PORT": "1"}.Get))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cfg.Level != "debug" || cfg.Port != 1 || cfg.Name != "a" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var cfg config
+		err := conf.Load(&cfg, conf.WithProviders(conf.NewEnvProvider(env{"LEVEL": "trace", "PORT": "-1"}.Get)))
+		want := "invalid value \"trace\" for level: must be one of debug, info, warn\n" +
+			"invalid value -1 for port: must be at least 1"
+		if err == nil || err.Error() != want {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		err = conf.Load(&cfg, conf.WithProviders(conf.NewEnvProvider(env{"PORT": "70000"}.Get)))
+		if err == nil || err.Error() != "invalid value 70000 for port: must be at most 65535" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		var cfg config
+		err := conf.Load(&cfg, conf.WithProviders(conf.NewFlagProvider([]string{"--port=0"})))
+		if err == nil || err.Error() != "invalid value 0 for port: must be at least 1" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		var cfg config
+		reg := conf.NewRegistry()
+		reg.Register("app", &cfg)
+		err := reg.Load(conf.WithProviders(conf.NewEnvProvider(env{"APP_NAME": "c"}.Get)))
+		if err == nil || err.Error() != `invalid value "c" for app.name: must be one of a, b` {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid tags", func(t *testing.T) {
+		var enum struct {
+			Port int `conf:"port" enum:"1,2"`
+		}
+		if err := conf.Load(&enum); err == nil || err.Error() != "field Port: enum tag on int field, only string fields are supported" {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		var bound struct {
+			Port int `conf:"port" min:"one"`
+		}
+		if err := conf.Load(&bound); err == nil || err.Error() != `field Port: invalid min tag "one": strconv.Atoi: parsing "one": invalid syntax` {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		var unset struct {
+			Workers int `conf:"workers" min:"1"`
+		}
+		if err := conf.Load(&unset); err == nil || err.Error() != "field Workers: min tag needs a default or the required option" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
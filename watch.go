@@ -0,0 +1,147 @@
+//go:build !tinygo
+
+package conf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultWatchInterval = time.Second
+
+// WithWatchFiles returns a LoadOption that makes Watch reload the
+// configuration when one of the given files changes. Files read by the
+// providers, like the .env file of an EnvProvider, are watched without it.
+func WithWatchFiles(paths ...string) LoadOption {
+	return func(c *loadConfig) {
+		c.watchFiles = append(c.watchFiles, paths...)
+	}
+}
+
+// WithWatchInterval returns a LoadOption that sets how often Watch checks the
+// files for changes. The default is one second, and d must be positive.
+func WithWatchInterval(d time.Duration) LoadOption {
+	return func(c *loadConfig) {
+		c.watchInterval = d
+	}
+}
+
+// WithReloadSignals returns a LoadOption that sets the signals that make
+// Watch reload the configuration. The default is SIGHUP.
+func WithReloadSignals(sigs ...os.Signal) LoadOption {
+	return func(c *loadConfig) {
+		c.reloadSignals = sigs
+	}
+}
+
+// WithOnError returns a LoadOption that sets a function Watch calls when
+// reloading fails. The previous configuration stays in use.
+func WithOnError(f func(err error)) LoadOption {
+	return func(c *loadConfig) {
+		c.onError = f
+	}
+}
+
+// Watcher holds the configuration loaded by Watch. Its Store can also be
+// reloaded manually.
+type Watcher[T any] struct {
+	*Store[T]
+}
+
+// Watch loads the configuration into cfg like Load, and keeps reloading it
+// until ctx is done whenever a watched file changes, a provider implementing
+// Notifier reports a change, or a reload signal arrives.
+//
+// Reloading works like Store.Reload. Since cfg is only the first
+// configuration, the current one must be retrieved with Get.
+func Watch[T any](ctx context.Context, cfg *T, opts ...LoadOption) (*Watcher[T], error) {
+	c := &loadConfig{
+		provider:      NewEnvProvider(os.Getenv),
+		watchInterval: defaultWatchInterval,
+		reloadSignals: []os.Signal{syscall.SIGHUP},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.watchInterval <= 0 {
+		return nil, fmt.Errorf("watch interval must be positive, got %s", c.watchInterval)
+	}
+
+	store, err := newStore(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher[T]{Store: store}
+
+	// The files are only known once the parameters have been registered
+	// by loading. Take the first fingerprint right away, so that changes
+	// made before the watching goroutine starts are not missed.
+	files := c.watchFiles
+	if p, ok := c.provider.(FileSource); ok {
+		files = append(files, p.Files()...)
+	}
+	stats := statFiles(files)
+
+	var notify <-chan struct{}
+	if p, ok := c.provider.(Notifier); ok {
+		if notify, err = p.Notify(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	if len(c.reloadSignals) > 0 {
+		signal.Notify(sigs, c.reloadSignals...)
+	}
+
+	go func() {
+		defer signal.Stop(sigs)
+
+		ticker := time.NewTicker(c.watchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+			case <-notify:
+			case <-ticker.C:
+				next := statFiles(files)
+				if next == stats {
+					continue
+				}
+				stats = next
+			}
+
+			if err := w.Reload(); err != nil && c.onError != nil {
+				c.onError(fmt.Errorf("failed to reload configuration: %w", err))
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// statFiles returns a fingerprint of the size and modification time of the
+// files, which changes when any of the files changes.
+func statFiles(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:missing;", file)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:%d:%d;", file, fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	return b.String()
+}
@@ -0,0 +1,242 @@
+//go:build !tinygo
+
+package conf_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/solhall/conf"
+	"github.com/solhall/conf/dotenv"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fileEnv returns a getenv function that reads the variables from a file on
+// every call, standing in for a .env file in the working directory.
+func fileEnv(t *testing.T, path string) func(string) string {
+	return func(key string) string {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Errorf("failed to open %s: %v", path, err)
+			return ""
+		}
+		defer f.Close()
+
+		m, err := dotenv.ParseReader(f)
+		if err != nil {
+			t.Errorf("failed to parse %s: %v", path, err)
+		}
+
+		return m[key]
+	}
+}
+
+func writeFile(t *testing.T, path, content string, mtime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set modification time of %s: %v", path, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	type mystruct struct {
+		Upstreams string `conf:"upstreams,required"`
+		Port      int    `conf:"port,default=8080"`
+	}
+
+	path := filepath.Join(t.TempDir(), "env")
+	now := time.Now()
+	writeFile(t, path, "UPSTREAMS=a,b", now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan []conf.Change, 1)
+	errs := make(chan error, 1)
+
+	var cfg mystruct
+	w, err := conf.Watch(ctx, &cfg,
+		conf.WithProviders(conf.NewEnvProvider(fileEnv(t, path))),
+		conf.WithWatchFiles(path),
+		conf.WithWatchInterval(10*time.Millisecond),
+		conf.WithOnChange(func(c []conf.Change) { changes <- c }),
+		conf.WithOnError(func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Get(); got.Upstreams != "a,b" || got.Port != 8080 {
+		t.Fatalf("unexpected initial config: %+v", got)
+	}
+
+	t.Run("reload on change", func(t *testing.T) {
+		first := w.Get()
+		writeFile(t, path, "UPSTREAMS=a,b,c", now.Add(time.Second))
+
+		select {
+		case c := <-changes:
+			want := []conf.Change{{Name: "upstreams", Old: "a,b", New: "a,b,c"}}
+			if diff := cmp.Diff(want, c); diff != "" {
+				t.Errorf("unexpected changes (-want +got):\n%s", diff)
+			}
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reload")
+		}
+
+		if got := w.Get(); got.Upstreams != "a,b,c" {
+			t.Errorf("expected value %s, got %s", "a,b,c", got.Upstreams)
+		}
+
+		if first.Upstreams != "a,b" {
+			t.Errorf("previous snapshot was modified: %+v", first)
+		}
+	})
+
+	t.Run("keep config on invalid reload", func(t *testing.T) {
+		writeFile(t, path, "PORT=80", now.Add(2*time.Second))
+
+		select {
+		case err := <-errs:
+			if !strings.Contains(err.Error(), "missing configuration parameters: upstreams") {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case c := <-changes:
+			t.Fatalf("unexpected changes: %v", c)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reload")
+		}
+
+		if got := w.Get(); got.Upstreams != "a,b,c" || got.Port != 8080 {
+			t.Errorf("unexpected config after failed reload: %+v", got)
+		}
+	})
+	t.Run("invalid interval", func(t *testing.T) {
+		var cfg struct {
+			Port int `conf:"port"`
+		}
+		_, err := conf.Watch(context.Background(), &cfg, conf.WithWatchInterval(0))
+		if err == nil || err.Error() != "watch interval must be positive, got 0s" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+// chdir changes the working directory to dir until the end of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Errorf("failed to restore working directory: %v", err)
+		}
+	})
+}
+
+func TestWatchDotEnv(t *testing.T) {
+	type mystruct struct {
+		Upstreams string `conf:"upstreams,required"`
+	}
+
+	chdir(t, t.TempDir())
+	now := time.Now()
+	writeFile(t, ".env", "UPSTREAMS=a,b", now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan []conf.Change, 1)
+	errs := make(chan error, 1)
+
+	// The .env file is watched through EnvProvider.Files, without
+	// WithWatchFiles.
+	provider := conf.NewEnvProvider(func(string) string { return "" }).WithDotEnv(nil)
+
+	var cfg mystruct
+	w, err := conf.Watch(ctx, &cfg,
+		conf.WithProviders(provider),
+		conf.WithWatchInterval(10*time.Millisecond),
+		conf.WithOnChange(func(c []conf.Change) { changes <- c }),
+		conf.WithOnError(func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Get(); got.Upstreams != "a,b" {
+		t.Fatalf("unexpected initial config: %+v", got)
+	}
+
+	writeFile(t, ".env", "UPSTREAMS=a,b,c", now.Add(time.Second))
+
+	select {
+	case c := <-changes:
+		want := []conf.Change{{Name: "upstreams", Old: "a,b", New: "a,b,c"}}
+		if diff := cmp.Diff(want, c); diff != "" {
+			t.Errorf("unexpected changes (-want +got):\n%s", diff)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+}
+
+func TestKVProviderWatch(t *testing.T) {
+	kv := conf.NewMemoryKV()
+	kv.Set("/app/db/host", []byte("a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan []conf.Change, 1)
+	errs := make(chan error, 1)
+
+	var cfg kvConfig
+	w, err := conf.Watch(ctx, &cfg,
+		conf.WithProviders(conf.NewKVProvider(kv, "/app/")),
+		conf.WithOnChange(func(c []conf.Change) { changes <- c }),
+		conf.WithOnError(func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kv.Set("/other/db/host", []byte("c"))
+	kv.Set("/app/db/host", []byte("b"))
+
+	select {
+	case c := <-changes:
+		want := []conf.Change{{Name: "db.host", Old: "a", New: "b"}}
+		if diff := cmp.Diff(want, c); diff != "" {
+			t.Errorf("unexpected changes (-want +got):\n%s", diff)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	if got := w.Get(); got.DB.Host != "b" {
+		t.Errorf("expected value %s, got %s", "b", got.DB.Host)
+	}
+}
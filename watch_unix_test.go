@@ -0,0 +1,71 @@
+//go:build unix && !tinygo
+
+package conf_test
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/solhall/conf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatchSignal(t *testing.T) {
+	type mystruct struct {
+		Upstreams string `conf:"upstreams,required"`
+	}
+
+	// The value is read by the watching goroutine, which is not
+	// synchronized with the test by the signal.
+	var upstreams atomic.Value
+	upstreams.Store("a,b")
+	getenv := func(key string) string {
+		if key == "UPSTREAMS" {
+			return upstreams.Load().(string)
+		}
+		return ""
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan []conf.Change, 1)
+	errs := make(chan error, 1)
+
+	// Nothing is watched but the default reload signal, SIGHUP.
+	var cfg mystruct
+	w, err := conf.Watch(ctx, &cfg,
+		conf.WithProviders(conf.NewEnvProvider(getenv)),
+		conf.WithOnChange(func(c []conf.Change) { changes <- c }),
+		conf.WithOnError(func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	upstreams.Store("a,b,c")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("failed to send SIGHUP: %v", err)
+	}
+
+	select {
+	case c := <-changes:
+		want := []conf.Change{{Name: "upstreams", Old: "a,b", New: "a,b,c"}}
+		if diff := cmp.Diff(want, c); diff != "" {
+			t.Errorf("unexpected changes (-want +got):\n%s", diff)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	if got := w.Get(); got.Upstreams != "a,b,c" {
+		t.Errorf("expected value %s, got %s", "a,b,c", got.Upstreams)
+	}
+}